@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/cooperspencer/gickup/metrics/prometheus"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/ktrysmt/go-bitbucket"
 	"github.com/rs/zerolog/log"
@@ -16,6 +17,7 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 	for _, repo := range conf.Source.BitBucket {
 		ran = true
 		client := bitbucket.NewBasicAuth(repo.Username, repo.Password)
+		client.HttpClient = prometheus.Instrument("bitbucket", client.HttpClient)
 		if repo.User == "" {
 			repo.User = repo.Username
 		}
@@ -56,8 +58,7 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			continue
 		}
 
-		include := types.GetMap(repo.Include)
-		exclude := types.GetMap(repo.Exclude)
+		sel := repo.Selector()
 
 		for _, r := range repositories.Items {
 			user := repo.User
@@ -67,48 +68,40 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				}
 			}
 
-			updated, err := time.Parse(time.RFC3339, r.UpdatedOn)
-			if err == nil {
-				if time.Since(updated) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-					continue
-				}
+			meta := types.RepoMeta{
+				Hoster:     types.GetHost(repo.URL),
+				Name:       r.Name,
+				Owner:      user,
+				FullPath:   r.Full_name,
+				Language:   r.Language,
+				Visibility: types.VisibilityOf(r.Is_private),
+				Fork:       r.Parent != nil,
 			}
 
-			if include[r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.Links["clone"].([]interface{})[0].(map[string]interface{})["href"].(string),
-					SSHURL:        r.Links["clone"].([]interface{})[1].(map[string]interface{})["href"].(string),
-					Token:         "",
-					Defaultbranch: r.Mainbranch.Name,
-					Origin:        repo,
-					Owner:         user,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Is_private,
-				})
+			if created, err := time.Parse(time.RFC3339, r.CreatedOn); err == nil {
+				meta.Created = created
+			}
 
-				continue
+			if updated, err := time.Parse(time.RFC3339, r.UpdatedOn); err == nil {
+				meta.LastActivity = updated
 			}
 
-			if exclude[r.Name] {
+			if !repo.Accept("bitbucket", sel, meta) {
 				continue
 			}
 
-			if len(include) == 0 {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.Links["clone"].([]interface{})[0].(map[string]interface{})["href"].(string),
-					SSHURL:        r.Links["clone"].([]interface{})[1].(map[string]interface{})["href"].(string),
-					Token:         "",
-					Defaultbranch: r.Mainbranch.Name,
-					Origin:        repo,
-					Owner:         user,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Is_private,
-				})
-			}
+			repos = append(repos, types.Repo{
+				Name:          r.Name,
+				URL:           r.Links["clone"].([]interface{})[0].(map[string]interface{})["href"].(string),
+				SSHURL:        r.Links["clone"].([]interface{})[1].(map[string]interface{})["href"].(string),
+				Token:         "",
+				Defaultbranch: r.Mainbranch.Name,
+				Origin:        repo,
+				Owner:         user,
+				Hoster:        types.GetHost(repo.URL),
+				Description:   r.Description,
+				Private:       r.Is_private,
+			})
 		}
 	}
 
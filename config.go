@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/cooperspencer/gickup/secrets"
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfigFile decodes every configuration in configfile, unknown fields are errors if strict is set.
+func loadConfigFile(configfile string, strict bool) ([]*types.Conf, error) {
+	conf := []*types.Conf{}
+	cfgdata, err := os.ReadFile(configfile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open config file from %s", configfile)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(cfgdata))
+	dir := filepath.Dir(configfile)
+	defaults := map[string]*yaml.Node{}
+
+	i := 0
+	for {
+		var c *types.Conf
+		err = decode(dec, &c, strict, dir, defaults)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if len(conf) > 0 {
+				return nil, fmt.Errorf("an error occured in the %d place of %s: %s", i, configfile, err.Error())
+			}
+
+			return nil, err
+		}
+
+		if !reflect.ValueOf(c).IsZero() {
+			// the paths are resolved once, so reloads compare them with the paths of the active configurations
+			for j := range c.Destination.Local {
+				c.Destination.Local[j].Path = localPath(c.Destination.Local[j].Path)
+			}
+
+			// reports are written after local backups changed the working directory
+			c.Report.JSON = localPath(c.Report.JSON)
+			c.Report.JUnit = localPath(c.Report.JUnit)
+
+			if len(conf) > 0 {
+				if !c.Metrics.PushConfigs.IsSet() {
+					c.Metrics.PushConfigs = conf[0].Metrics.PushConfigs
+				}
+				if c.Metrics.Prometheus.Pushgateway.URL == "" {
+					c.Metrics.Prometheus.Pushgateway = conf[0].Metrics.Prometheus.Pushgateway
+				}
+			}
+			conf = append(conf, c)
+			i++
+		}
+	}
+
+	return conf, nil
+}
+
+func readConfigFile(configfile string) []*types.Conf {
+	conf, err := loadConfigFile(configfile, false)
+	if err != nil {
+		log.Fatal().
+			Str("stage", "readconfig").
+			Str("file", configfile).
+			Msg(err.Error())
+	}
+
+	return conf
+}
+
+// decode reads the next document of dec into c, after merging includes and templates
+// and interpolating environment variables and files.
+// Templates defined in earlier documents are available in later ones.
+func decode(dec *yaml.Decoder, c **types.Conf, strict bool, dir string, defaults map[string]*yaml.Node) error {
+	node := yaml.Node{}
+	if err := dec.Decode(&node); err != nil {
+		return err
+	}
+
+	if err := resolveIncludes(&node, dir, 0); err != nil {
+		return err
+	}
+
+	collectDefaults(&node, defaults)
+
+	if err := resolveExtends(&node, defaults); err != nil {
+		return err
+	}
+
+	if err := secrets.InterpolateNode(&node); err != nil {
+		return err
+	}
+
+	// yaml.Node.Decode doesn't check for unknown fields, so they are checked before
+	if strict {
+		if err := knownFields(&node, reflect.TypeOf(c)); err != nil {
+			return err
+		}
+	}
+
+	// the node itself is decoded, so the errors have the lines of the configfile
+	return node.Decode(c)
+}
+
+// knownFields checks that every key of the mappings of node is a field of t, like yaml.Decoder.KnownFields.
+func knownFields(node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			if err := knownFields(n, t); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return knownFields(node.Alias, t)
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+
+		for _, n := range node.Content {
+			if err := knownFields(n, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			switch {
+			case key.Value == "<<":
+				if err := knownFields(value, t); err != nil {
+					return err
+				}
+			case t.Kind() == reflect.Map:
+				if err := knownFields(value, t.Elem()); err != nil {
+					return err
+				}
+			case t.Kind() == reflect.Struct:
+				field, ok := yamlField(t, key.Value)
+				if !ok {
+					return fmt.Errorf("yaml: unmarshal errors:\n  line %d: field %s not found in type %s", key.Line, key.Value, t.String())
+				}
+
+				if err := knownFields(value, field.Type); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// yamlField returns the field of the struct t which name is decoded into.
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+
+		if key == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
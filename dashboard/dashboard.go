@@ -0,0 +1,110 @@
+package dashboard
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/cooperspencer/gickup/logger"
+	"github.com/cooperspencer/gickup/status"
+	"github.com/cooperspencer/gickup/types"
+)
+
+//go:embed index.html
+var files embed.FS
+
+var page = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"size": humanSize,
+	"time": formatTime,
+}).ParseFS(files, "index.html"))
+
+// Configuration is the view of a single configuration.
+type Configuration struct {
+	Number       int
+	Cron         string
+	NextRun      string
+	Sources      []string
+	Destinations []string
+	Results      []status.Result
+	Failed       int
+}
+
+// Page is the data passed to the template.
+type Page struct {
+	Generated      string
+	Configurations []Configuration
+}
+
+// Handler returns the http handler rendering the dashboard for the configurations returned by confs.
+func Handler(confs func() []*types.Conf) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := Page{Generated: formatTime(time.Now())}
+		results := status.Results()
+
+		for num, conf := range confs() {
+			c := Configuration{
+				Number:       num,
+				Cron:         conf.Cron,
+				Sources:      conf.Source.Describe(),
+				Destinations: conf.Destination.Describe(),
+			}
+
+			if conf.HasValidCronSpec() {
+				next, err := conf.GetNextRun()
+				if err == nil {
+					c.NextRun = formatTime(*next)
+				}
+			}
+
+			for _, result := range results {
+				if result.Config == num {
+					c.Results = append(c.Results, result)
+					if !result.Success {
+						c.Failed++
+					}
+				}
+			}
+
+			data.Configurations = append(data.Configurations, c)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, data); err != nil {
+			logger.Background().Error().Str("stage", "dashboard").Msg(err.Error())
+		}
+	})
+}
+
+// Serve starts the dashboard listener.
+func Serve(conf types.DashboardConfig, confs func() []*types.Conf) {
+	logger.Background().Info().
+		Str("listenAddr", conf.ListenAddr).
+		Str("endpoint", conf.GetEndpoint()).
+		Msg("Starting dashboard listener")
+
+	mux := http.NewServeMux()
+	mux.Handle(conf.GetEndpoint(), Handler(confs))
+
+	err := http.ListenAndServe(conf.ListenAddr, mux)
+	logger.Background().Fatal().
+		Str("listenAddr", conf.ListenAddr).
+		Str("endpoint", conf.GetEndpoint()).
+		Msg(err.Error())
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+func humanSize(size int64) string {
+	if size <= 0 {
+		return "-"
+	}
+
+	return types.FormatSize(size)
+}
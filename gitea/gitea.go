@@ -1,12 +1,13 @@
 package gitea
 
 import (
-	"strings"
-	"time"
-
 	"code.gitea.io/sdk/gitea"
+	"github.com/cooperspencer/gickup/metrics/prometheus"
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/tracing"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func getOrgVisibility(visibility string) gitea.VisibleType {
@@ -46,7 +47,13 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		Str("url", d.URL).
 		Msgf("mirroring %s to %s", types.Blue(r.Name), d.URL)
 
-	giteaclient, err := gitea.NewClient(d.URL, gitea.SetToken(d.GetToken()))
+	token, err := d.GetToken()
+	if err != nil {
+		log.Error().Str("stage", "gitea").Str("url", d.URL).Msg(err.Error())
+		return false
+	}
+
+	giteaclient, err := gitea.NewClient(d.URL, gitea.SetToken(token), gitea.SetHTTPClient(prometheus.Instrument("gitea", nil)))
 	if err != nil {
 		log.Error().Str("stage", "gitea").Str("url", d.URL).Msg(err.Error())
 		return false
@@ -65,10 +72,19 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		d.User = r.Owner
 	}
 
+	destination := "gitea " + d.URL
+
 	if d.User != "" {
 		user, _, err = giteaclient.GetUserInfo(d.User)
 		if err != nil {
 			if d.CreateOrg {
+				if dry {
+					plan.Add(r.Step(destination, plan.CreateOrg, d.User))
+					plan.Add(r.Step(destination, plan.CreateMirror, ""))
+
+					return true
+				}
+
 				org, _, err := giteaclient.CreateOrg(gitea.CreateOrgOption{
 					Name:       d.User,
 					Visibility: orgvisibilty,
@@ -93,12 +109,14 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 
 	}
 
-	if dry {
-		return true
-	}
-
 	repo, _, err := giteaclient.GetRepo(user.UserName, r.Name)
 	if err != nil {
+		if dry {
+			plan.Add(r.Step(destination, plan.CreateMirror, ""))
+
+			return true
+		}
+
 		opts := gitea.MigrateRepoOption{
 			RepoName:    r.Name,
 			RepoOwner:   user.UserName,
@@ -124,7 +142,9 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 			}
 		}
 
+		span := tracing.Start("gitea migrate", attribute.String("repo", r.Name), attribute.String("url", d.URL))
 		_, _, err := giteaclient.MigrateRepo(opts)
+		span.End(err)
 		if err != nil {
 			log.Error().
 				Str("stage", "gitea").
@@ -140,13 +160,19 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 
 		return true
 	}
-	if repo.Mirror {
+	if !repo.Mirror {
+		plan.Add(r.Step(destination, plan.Skip, "exists and isn't a mirror"))
+	} else if dry {
+		plan.Add(r.Step(destination, plan.Sync, ""))
+	} else {
 		log.Info().
 			Str("stage", "gitea").
 			Str("url", d.URL).
 			Msgf("mirror of %s already exists, syncing instead", types.Blue(r.Name))
 
+		span := tracing.Start("gitea mirror sync", attribute.String("repo", r.Name), attribute.String("url", d.URL))
 		_, err := giteaclient.MirrorSync(user.UserName, repo.Name)
+		span.End(err)
 		if err != nil {
 			log.Error().
 				Str("stage", "gitea").
@@ -197,11 +223,19 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 		gitearepos := []*gitea.Repository{}
 
 		var client *gitea.Client
-		token := repo.GetToken()
+		token, err := repo.GetToken()
+		if err != nil {
+			log.Error().
+				Str("stage", "gitea").
+				Str("url", repo.URL).
+				Msg(err.Error())
+			continue
+		}
+
 		if token != "" {
-			client, err = gitea.NewClient(repo.URL, gitea.SetToken(token))
+			client, err = gitea.NewClient(repo.URL, gitea.SetToken(token), gitea.SetHTTPClient(prometheus.Instrument("gitea", nil)))
 		} else {
-			client, err = gitea.NewClient(repo.URL)
+			client, err = gitea.NewClient(repo.URL, gitea.SetHTTPClient(prometheus.Instrument("gitea", nil)))
 		}
 
 		if token != "" && repo.User == "" {
@@ -252,114 +286,8 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			}
 		}
 
-		include := types.GetMap(repo.Include)
-		exclude := types.GetMap(repo.Exclude)
-		includeorgs := types.GetMap(repo.IncludeOrgs)
-		excludeorgs := types.GetMap(repo.ExcludeOrgs)
-		for i := range repo.Filter.Languages {
-			repo.Filter.Languages[i] = strings.ToLower(repo.Filter.Languages[i])
-		}
-		languages := types.GetMap(repo.Filter.Languages)
-
-		for _, r := range gitearepos {
-			if repo.Filter.ExcludeArchived {
-				if r.Archived {
-					continue
-				}
-			}
-
-			if len(repo.Filter.Languages) > 0 {
-				langs, _, err := client.GetRepoLanguages(r.Owner.UserName, r.Name)
-				if err != nil {
-					log.Error().
-						Str("stage", "gitea").
-						Str("url", repo.URL).
-						Msg(err.Error())
-					continue
-				} else {
-					language := ""
-					percentage := int64(0)
-					for lang, percent := range langs {
-						if percent > percentage {
-							language = lang
-						}
-					}
-					if !languages[strings.ToLower(language)] {
-						continue
-					}
-				}
-			}
-
-			if r.Stars < repo.Filter.Stars {
-				continue
-			}
-			if time.Since(r.Updated) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-				continue
-			}
-			if include[r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
-					Token:         token,
-					Defaultbranch: r.DefaultBranch,
-					Origin:        repo,
-					Owner:         r.Owner.UserName,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Private,
-				})
-				if r.HasWiki && repo.Wiki && types.StatRemote(r.CloneURL, r.SSHURL, repo) {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
+		sel := repo.Selector()
 
-				continue
-			}
-
-			if exclude[r.Name] {
-				continue
-			}
-
-			if len(repo.Include) == 0 {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
-					Token:         token,
-					Defaultbranch: r.DefaultBranch,
-					Origin:        repo,
-					Owner:         r.Owner.UserName,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Private,
-				})
-				if r.HasWiki && repo.Wiki && types.StatRemote(r.CloneURL, r.SSHURL, repo) {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
-			}
-		}
 		orgopt := gitea.ListOptions{Page: 1, PageSize: 50}
 		orgs := []*gitea.Organization{}
 		if token != "" {
@@ -378,39 +306,45 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				orgopt.Page++
 			}
 		}
-		orgrepos := []*gitea.Repository{}
 		for _, org := range orgs {
-			orgopt.Page = 1
-			if excludeorgs[org.UserName] {
+			if !sel.OrgSelected(org.UserName) {
 				continue
 			}
+			orgopt.Page = 1
 			for {
-				if len(includeorgs) > 0 {
-					if includeorgs[org.UserName] {
-						o := getOrgRepos(client, org, orgopt, repo)
-						if len(o) == 0 {
-							break
-						}
-						orgrepos = append(orgrepos, o...)
-					}
-				} else {
-					o := getOrgRepos(client, org, orgopt, repo)
-					if len(o) == 0 {
-						break
-					}
-					orgrepos = append(orgrepos, o...)
+				o := getOrgRepos(client, org, orgopt, repo)
+				if len(o) == 0 {
+					break
 				}
+				gitearepos = append(gitearepos, o...)
 				orgopt.Page++
 			}
 		}
-		for _, r := range orgrepos {
-			if repo.Filter.ExcludeArchived {
-				if r.Archived {
-					continue
-				}
+
+		for _, r := range gitearepos {
+			visibility := types.VisibilityOf(r.Private)
+			if r.Internal {
+				visibility = types.VisibilityInternal
 			}
 
-			if len(repo.Filter.Languages) > 0 {
+			meta := types.RepoMeta{
+				Hoster:       types.GetHost(repo.URL),
+				Name:         r.Name,
+				Owner:        r.Owner.UserName,
+				FullPath:     r.FullName,
+				Stars:        r.Stars,
+				Visibility:   visibility,
+				Archived:     r.Archived,
+				Fork:         r.Fork,
+				Template:     r.Template,
+				Mirror:       r.Mirror,
+				Empty:        r.Empty,
+				Size:         int64(r.Size) * 1024,
+				Created:      r.Created,
+				LastActivity: r.Updated,
+			}
+
+			if repo.Filter.NeedsLanguage() {
 				langs, _, err := client.GetRepoLanguages(r.Owner.UserName, r.Name)
 				if err != nil {
 					log.Error().
@@ -418,66 +352,59 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 						Str("url", repo.URL).
 						Msg(err.Error())
 					continue
-				} else {
-					language := ""
-					percentage := int64(0)
-					for lang, percent := range langs {
-						if percent > percentage {
-							language = lang
-						}
-					}
-					if !languages[strings.ToLower(language)] {
-						continue
+				}
+
+				percentage := int64(0)
+				for lang, percent := range langs {
+					if percent > percentage {
+						meta.Language = lang
+						percentage = percent
 					}
 				}
 			}
 
-			if r.Stars < repo.Filter.Stars {
-				continue
+			if repo.Filter.NeedsTopics() {
+				topics, _, err := client.ListRepoTopics(r.Owner.UserName, r.Name, gitea.ListRepoTopicsOptions{})
+				if err != nil {
+					log.Error().
+						Str("stage", "gitea").
+						Str("url", repo.URL).
+						Msg(err.Error())
+					continue
+				}
+
+				meta.Topics = topics
 			}
-			if time.Since(r.Updated) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
+
+			if !repo.Accept("gitea", sel, meta) {
 				continue
 			}
-			if include[r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
-					Token:         token,
-					Defaultbranch: r.DefaultBranch,
-					Origin:        repo,
-					Owner:         r.Owner.UserName,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Private,
-				})
-				if r.HasWiki && repo.Wiki && types.StatRemote(r.CloneURL, r.SSHURL, repo) {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
 
-				continue
+			gr := types.Repo{
+				Name:          r.Name,
+				URL:           r.CloneURL,
+				SSHURL:        r.SSHURL,
+				Token:         token,
+				Defaultbranch: r.DefaultBranch,
+				Origin:        repo,
+				Owner:         r.Owner.UserName,
+				Hoster:        types.GetHost(repo.URL),
+				Description:   r.Description,
+				Private:       r.Private,
+				Size:          meta.Size,
 			}
 
-			if exclude[r.Name] {
-				continue
+			if repo.ParentRefs && r.Parent != nil {
+				gr.ParentURL = r.Parent.CloneURL
+				gr.ParentSSHURL = r.Parent.SSHURL
 			}
 
-			if len(repo.Include) == 0 {
+			repos = append(repos, gr)
+			if r.HasWiki && repo.Wiki && types.StatRemote(r.CloneURL, r.SSHURL, repo) {
 				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
+					Name:          r.Name + ".wiki",
+					URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
+					SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
 					Token:         token,
 					Defaultbranch: r.DefaultBranch,
 					Origin:        repo,
@@ -486,20 +413,6 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 					Description:   r.Description,
 					Private:       r.Private,
 				})
-				if r.HasWiki && repo.Wiki && types.StatRemote(r.CloneURL, r.SSHURL, repo) {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
 			}
 		}
 	}
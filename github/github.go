@@ -2,9 +2,9 @@ package github
 
 import (
 	"context"
-	"strings"
-	"time"
+	"errors"
 
+	"github.com/cooperspencer/gickup/metrics/prometheus"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/google/go-github/v41/github"
 	"github.com/rs/zerolog/log"
@@ -45,7 +45,7 @@ func getv4(token, user string) []V4Repo {
 		&oauth2.Token{AccessToken: token},
 	)
 	oauth2Client := oauth2.NewClient(context.Background(), tokenSource)
-	client := githubv4.NewClient(oauth2Client)
+	client := githubv4.NewClient(prometheus.Instrument("github", oauth2Client))
 
 	var query Query
 	variables := map[string]interface{}{
@@ -127,18 +127,25 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 
 		i := 1
 		githubrepos := []*github.Repository{}
-		token := repo.GetToken()
+		token, err := repo.GetToken()
+		if err != nil {
+			log.Error().
+				Str("stage", "github").
+				Str("url", "https://github.com").
+				Msg(err.Error())
+			continue
+		}
 
 		var client *github.Client
 		if token == "" {
-			client = github.NewClient(nil)
+			client = github.NewClient(prometheus.Instrument("github", nil))
 		} else {
 			ts := oauth2.StaticTokenSource(
 				&oauth2.Token{AccessToken: token},
 			)
 			tc := oauth2.NewClient(context.TODO(), ts)
 
-			client = github.NewClient(tc)
+			client = github.NewClient(prometheus.Instrument("github", tc))
 		}
 
 		v4user := repo.User
@@ -217,107 +224,142 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			}
 		}
 
-		include := types.GetMap(repo.Include)
-		includeorgs := types.GetMap(repo.IncludeOrgs)
-		exclude := types.GetMap(repo.Exclude)
-		excludeorgs := types.GetMap(repo.ExcludeOrgs)
-		for i := range repo.Filter.Languages {
-			repo.Filter.Languages[i] = strings.ToLower(repo.Filter.Languages[i])
-		}
-		languages := types.GetMap(repo.Filter.Languages)
+		sel := repo.Selector()
 
 		for _, r := range githubrepos {
-			if repo.Filter.ExcludeArchived {
-				if r.Archived != nil {
-					if *r.Archived {
-						continue
-					}
-				}
-			}
-			if len(repo.Filter.Languages) > 0 {
-				if r.Language != nil {
-					if !languages[strings.ToLower(*r.Language)] {
-						continue
-					}
-				}
+			owner := r.GetOwner().GetLogin()
+			visibility := r.GetVisibility()
+			if visibility == "" {
+				visibility = types.VisibilityOf(r.GetPrivate())
 			}
-			if *r.StargazersCount < repo.Filter.Stars {
-				continue
+
+			meta := types.RepoMeta{
+				Hoster:       "github.com",
+				Name:         r.GetName(),
+				Owner:        owner,
+				FullPath:     r.GetFullName(),
+				Stars:        r.GetStargazersCount(),
+				Language:     r.GetLanguage(),
+				Topics:       r.Topics,
+				Visibility:   visibility,
+				Archived:     r.GetArchived(),
+				Fork:         r.GetFork(),
+				Template:     r.GetIsTemplate(),
+				Mirror:       r.GetMirrorURL() != "",
+				Size:         int64(r.GetSize()) * 1024,
+				Created:      r.GetCreatedAt().Time,
+				LastActivity: r.GetPushedAt().Time,
 			}
-			if time.Since(r.PushedAt.Time) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-				continue
+
+			if repo.Filter.NeedsEmpty() {
+				meta.Empty = isEmpty(client, r)
 			}
 
-			if include[*r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.GetName(),
-					URL:           r.GetCloneURL(),
-					SSHURL:        r.GetSSHURL(),
-					Token:         token,
-					Defaultbranch: r.GetDefaultBranch(),
-					Origin:        repo,
-					Owner:         r.GetOwner().GetLogin(),
-					Hoster:        "github.com",
-					Description:   r.GetDescription(),
-					Private:       r.GetPrivate(),
-				})
-				wiki := addWiki(*r, repo, token)
-				if wiki.Name != "" {
-					repos = append(repos, wiki)
-				}
+			if r.GetFork() {
+				meta.AheadOfParent = aheadOfParent(client, r)
+			}
 
+			if !repo.Accept("github", sel, meta) {
 				continue
 			}
 
-			if exclude[*r.Name] {
+			if !sel.Included(meta.Name, owner, meta.FullPath) && !sel.OrgSelected(owner) {
+				types.Skip("github", meta, "organization not included")
 				continue
 			}
 
-			if excludeorgs[r.GetOwner().GetLogin()] {
-				continue
+			gr := types.Repo{
+				Name:          r.GetName(),
+				URL:           r.GetCloneURL(),
+				SSHURL:        r.GetSSHURL(),
+				Token:         token,
+				Defaultbranch: r.GetDefaultBranch(),
+				Origin:        repo,
+				Owner:         owner,
+				Hoster:        "github.com",
+				Description:   r.GetDescription(),
+				Private:       r.GetPrivate(),
+				Size:          meta.Size,
 			}
 
-			if len(repo.Include) == 0 {
-				if len(includeorgs) > 0 {
-					if includeorgs[r.GetOwner().GetLogin()] {
-						repos = append(repos, types.Repo{
-							Name:          r.GetName(),
-							URL:           r.GetCloneURL(),
-							SSHURL:        r.GetSSHURL(),
-							Token:         token,
-							Defaultbranch: r.GetDefaultBranch(),
-							Origin:        repo,
-							Owner:         r.GetOwner().GetLogin(),
-							Hoster:        "github.com",
-							Description:   r.GetDescription(),
-							Private:       r.GetPrivate(),
-						})
-						wiki := addWiki(*r, repo, token)
-						if wiki.Name != "" {
-							repos = append(repos, wiki)
-						}
-					}
+			if repo.ParentRefs && r.GetFork() {
+				parent, err := parentOf(client, r)
+				if err != nil {
+					log.Error().
+						Str("stage", "github").
+						Str("url", "https://github.com").
+						Msg(err.Error())
 				} else {
-					repos = append(repos, types.Repo{
-						Name:          r.GetName(),
-						URL:           r.GetCloneURL(),
-						SSHURL:        r.GetSSHURL(),
-						Token:         token,
-						Defaultbranch: r.GetDefaultBranch(),
-						Origin:        repo,
-						Owner:         r.GetOwner().GetLogin(),
-						Hoster:        "github.com",
-						Description:   r.GetDescription(),
-						Private:       r.GetPrivate(),
-					})
-					wiki := addWiki(*r, repo, token)
-					if wiki.Name != "" {
-						repos = append(repos, wiki)
-					}
+					gr.ParentURL = parent.GetCloneURL()
+					gr.ParentSSHURL = parent.GetSSHURL()
 				}
 			}
+
+			repos = append(repos, gr)
+			wiki := addWiki(*r, repo, token)
+			if wiki.Name != "" {
+				repos = append(repos, wiki)
+			}
 		}
 	}
 
 	return repos, ran
 }
+
+// parentOf returns the parent of the fork r, the list endpoints don't include it.
+func parentOf(client *github.Client, r *github.Repository) (*github.Repository, error) {
+	if r.Parent == nil {
+		full, _, err := client.Repositories.Get(context.TODO(), r.GetOwner().GetLogin(), r.GetName())
+		if err != nil {
+			return nil, err
+		}
+
+		if full.Parent == nil {
+			return nil, errors.New("fork without parent")
+		}
+
+		r.Parent = full.Parent
+	}
+
+	return r.Parent, nil
+}
+
+// isEmpty checks if a repository has no branches, the size github reports is only updated
+// some time after a push and is 0 for recently pushed repositories.
+func isEmpty(client *github.Client, r *github.Repository) bool {
+	if r.GetSize() > 0 {
+		return false
+	}
+
+	branches, _, err := client.Repositories.ListBranches(context.TODO(), r.GetOwner().GetLogin(), r.GetName(),
+		&github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		log.Error().
+			Str("stage", "github").
+			Str("repo", r.GetFullName()).
+			Msgf("can't check if the repository is empty, keeping it: %s", err.Error())
+
+		return false
+	}
+
+	return len(branches) == 0
+}
+
+// aheadOfParent counts the commits on the default branch of the fork r which aren't in the default branch of its parent.
+func aheadOfParent(client *github.Client, r *github.Repository) func() (int, error) {
+	return func() (int, error) {
+		parent, err := parentOf(client, r)
+		if err != nil {
+			return 0, err
+		}
+
+		comparison, _, err := client.Repositories.CompareCommits(context.TODO(),
+			parent.GetOwner().GetLogin(), parent.GetName(), parent.GetDefaultBranch(),
+			r.GetOwner().GetLogin()+":"+r.GetDefaultBranch(), nil)
+		if err != nil {
+			return 0, err
+		}
+
+		return comparison.GetAheadBy(), nil
+	}
+}
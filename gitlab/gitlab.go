@@ -3,24 +3,36 @@ package gitlab
 import (
 	"fmt"
 	"path"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/cooperspencer/gickup/metrics/prometheus"
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/tracing"
 	"github.com/cooperspencer/gickup/types"
+	"github.com/hashicorp/go-retryablehttp"
 	"github.com/rs/zerolog/log"
 	"github.com/xanzy/go-gitlab"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Backup TODO.
 func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 	var gitlabclient *gitlab.Client
-	token := d.GetToken()
-	var err error
+	token, err := d.GetToken()
+	if err != nil {
+		log.Error().
+			Str("stage", "gitlab").
+			Str("url", d.URL).
+			Msg(err.Error())
+		return false
+	}
+
 	if d.URL == "" {
 		d.URL = "https://gitlab.com"
-		gitlabclient, err = gitlab.NewClient(token)
+		gitlabclient, err = gitlab.NewClient(token, gitlab.WithHTTPClient(prometheus.Instrument("gitlab", nil)))
 	} else {
-		gitlabclient, err = gitlab.NewClient(token, gitlab.WithBaseURL(d.URL))
+		gitlabclient, err = gitlab.NewClient(token, gitlab.WithBaseURL(d.URL), gitlab.WithHTTPClient(prometheus.Instrument("gitlab", nil)))
 	}
 
 	if err != nil {
@@ -56,7 +68,17 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		}
 	}
 
-	if dry || found {
+	destination := "gitlab " + d.URL
+
+	if found {
+		plan.Add(r.Step(destination, plan.Skip, "mirror exists, gitlab keeps it up to date"))
+
+		return true
+	}
+
+	if dry {
+		plan.Add(r.Step(destination, plan.CreateMirror, ""))
+
 		return true
 	}
 
@@ -83,7 +105,9 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		Visibility:  gitlab.Visibility(visibility),
 	}
 
+	span := tracing.Start("gitlab create mirror", attribute.String("repo", r.Name), attribute.String("url", d.URL))
 	_, _, err = gitlabclient.Projects.CreateProject(opts)
+	span.End(err)
 	if err != nil {
 		log.Error().
 			Str("stage", "gitlab").
@@ -118,8 +142,16 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			Msgf("grabbing repositories from %s", repo.User)
 		gitlabrepos := []*gitlab.Project{}
 		gitlabgrouprepos := map[string][]*gitlab.Project{}
-		token := repo.GetToken()
-		client, err := gitlab.NewClient(token, gitlab.WithBaseURL(repo.URL))
+		token, err := repo.GetToken()
+		if err != nil {
+			log.Error().
+				Str("stage", "gitlab").
+				Str("url", repo.URL).
+				Msg(err.Error())
+			continue
+		}
+
+		client, err := gitlab.NewClient(token, gitlab.WithBaseURL(repo.URL), gitlab.WithHTTPClient(prometheus.Instrument("gitlab", nil)))
 		if err != nil {
 			log.Error().
 				Str("stage", "gitlab").
@@ -129,6 +161,9 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 		}
 
 		opt := &gitlab.ListProjectsOptions{}
+		if token != "" {
+			opt.Statistics = gitlab.Bool(true)
+		}
 		users, _, err := client.Users.ListUsers(&gitlab.ListUsersOptions{Username: &repo.User})
 		if err != nil {
 			log.Error().
@@ -183,94 +218,52 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			}
 		}
 
-		include := types.GetMap(repo.Include)
-		includeorgs := types.GetMap(repo.IncludeOrgs)
-		exclude := types.GetMap(repo.Exclude)
-		excludeorgs := types.GetMap(repo.ExcludeOrgs)
-		languages := types.GetMap(repo.Filter.Languages)
+		sel := repo.Selector()
 
 		for _, r := range gitlabrepos {
-			if repo.Filter.ExcludeArchived {
-				if r.Archived {
-					continue
-				}
-			}
-
-			if len(repo.Filter.Languages) > 0 {
-				langs, _, err := client.Projects.GetProjectLanguages(r.ID)
-				if err != nil {
-					log.Error().
-						Str("stage", "gitlab").
-						Str("url", repo.URL).
-						Msg(err.Error())
-					continue
-				} else {
-					language := ""
-					percentage := float32(0)
-
-					for lang, percent := range *langs {
-						if percent > percentage {
-							language = lang
-						}
-					}
-					if !languages[strings.ToLower(language)] {
-						continue
-					}
-				}
-			}
-
-			if r.StarCount < repo.Filter.Stars {
+			meta, err := projectMeta(r, client, repo)
+			if err != nil {
+				log.Error().
+					Str("stage", "gitlab").
+					Str("url", repo.URL).
+					Msg(err.Error())
 				continue
 			}
-			if time.Since(*r.LastActivityAt) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
+
+			if !repo.Accept("gitlab", sel, meta) {
 				continue
 			}
-			if include[r.Name] {
-				if r.RepositoryAccessLevel != gitlab.DisabledAccessControl {
-					repos = append(repos, types.Repo{
-						Name:          r.Path,
-						URL:           r.HTTPURLToRepo,
-						SSHURL:        r.SSHURLToRepo,
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Namespace.FullPath,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Visibility == gitlab.PrivateVisibility,
-					})
+
+			if r.RepositoryAccessLevel != gitlab.DisabledAccessControl {
+				gr := types.Repo{
+					Name:          r.Path,
+					URL:           r.HTTPURLToRepo,
+					SSHURL:        r.SSHURLToRepo,
+					Token:         token,
+					Defaultbranch: r.DefaultBranch,
+					Origin:        repo,
+					Owner:         r.Namespace.FullPath,
+					Hoster:        types.GetHost(repo.URL),
+					Description:   r.Description,
+					Private:       r.Visibility == gitlab.PrivateVisibility,
+					Size:          meta.Size,
 				}
 
-				if r.WikiEnabled && repo.Wiki {
-					if activeWiki(r, client, repo) {
-						httpURLToRepo := types.DotGitRx.ReplaceAllString(r.HTTPURLToRepo, ".wiki.git")
-						sshURLToRepo := types.DotGitRx.ReplaceAllString(r.SSHURLToRepo, ".wiki.git")
-						repos = append(repos, types.Repo{
-							Name:          r.Path + ".wiki",
-							URL:           httpURLToRepo,
-							SSHURL:        sshURLToRepo,
-							Token:         token,
-							Defaultbranch: r.DefaultBranch,
-							Origin:        repo,
-							Owner:         r.Namespace.FullPath,
-							Hoster:        types.GetHost(repo.URL),
-							Description:   r.Description,
-							Private:       r.Visibility == gitlab.PrivateVisibility,
-						})
-					}
+				if repo.ParentRefs && r.ForkedFromProject != nil {
+					addParent(&gr, r, client, repo)
 				}
 
-				continue
-			}
-			if exclude[r.Name] {
-				continue
+				repos = append(repos, gr)
 			}
-			if len(include) == 0 {
-				if r.RepositoryAccessLevel != gitlab.DisabledAccessControl {
+
+			if r.WikiEnabled && repo.Wiki {
+				if activeWiki(r, client, repo) {
+					httpURLToRepo := types.DotGitRx.ReplaceAllString(r.HTTPURLToRepo, ".wiki.git")
+					sshURLToRepo := types.DotGitRx.ReplaceAllString(r.SSHURLToRepo, ".wiki.git")
 					repos = append(repos, types.Repo{
-						Name:          r.Path,
-						URL:           r.HTTPURLToRepo,
-						SSHURL:        r.SSHURLToRepo,
+						Name:          r.Path + ".wiki",
+						URL:           httpURLToRepo,
+						SSHURL:        sshURLToRepo,
 						Token:         token,
 						Defaultbranch: r.DefaultBranch,
 						Origin:        repo,
@@ -280,25 +273,6 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 						Private:       r.Visibility == gitlab.PrivateVisibility,
 					})
 				}
-
-				if r.WikiEnabled && repo.Wiki {
-					if activeWiki(r, client, repo) {
-						httpURLToRepo := types.DotGitRx.ReplaceAllString(r.HTTPURLToRepo, ".wiki.git")
-						sshURLToRepo := types.DotGitRx.ReplaceAllString(r.SSHURLToRepo, ".wiki.git")
-						repos = append(repos, types.Repo{
-							Name:          r.Path + ".wiki",
-							URL:           httpURLToRepo,
-							SSHURL:        sshURLToRepo,
-							Token:         token,
-							Defaultbranch: r.DefaultBranch,
-							Origin:        repo,
-							Owner:         r.Namespace.FullPath,
-							Hoster:        types.GetHost(repo.URL),
-							Description:   r.Description,
-							Private:       r.Visibility == gitlab.PrivateVisibility,
-						})
-					}
-				}
 			}
 		}
 
@@ -355,48 +329,54 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			}
 			for k, gr := range gitlabgrouprepos {
 				for _, r := range gr {
-					if repo.Filter.ExcludeArchived {
-						if r.Archived {
-							continue
-						}
+					meta, err := projectMeta(r, client, repo)
+					if err != nil {
+						log.Error().
+							Str("stage", "gitlab").
+							Str("url", repo.URL).
+							Msg(err.Error())
+						continue
 					}
 
-					if len(repo.Filter.Languages) > 0 {
-						langs, _, err := client.Projects.GetProjectLanguages(r.ID)
-						if err != nil {
-							log.Error().
-								Str("stage", "gitlab").
-								Str("url", repo.URL).
-								Msg(err.Error())
-							continue
-						} else {
-							language := ""
-							percentage := float32(0)
-
-							for lang, percent := range *langs {
-								if percent > percentage {
-									language = lang
-								}
-							}
-							if !languages[strings.ToLower(language)] {
-								continue
-							}
-						}
+					if !repo.Accept("gitlab", sel, meta) {
+						continue
 					}
 
-					if r.StarCount < repo.Filter.Stars {
+					if !sel.Included(r.Name, r.Namespace.FullPath, r.PathWithNamespace) && !sel.OrgSelected(r.Namespace.FullPath) {
+						types.Skip("gitlab", meta, "organization not included")
 						continue
 					}
-					if time.Since(*r.LastActivityAt) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-						continue
+
+					if r.RepositoryAccessLevel != gitlab.DisabledAccessControl {
+						gr := types.Repo{
+							Name:          r.Path,
+							URL:           r.HTTPURLToRepo,
+							SSHURL:        r.SSHURLToRepo,
+							Token:         token,
+							Defaultbranch: r.DefaultBranch,
+							Origin:        repo,
+							Owner:         k,
+							Hoster:        types.GetHost(repo.URL),
+							Description:   r.Description,
+							Private:       r.Visibility == gitlab.PrivateVisibility,
+							Size:          meta.Size,
+						}
+
+						if repo.ParentRefs && r.ForkedFromProject != nil {
+							addParent(&gr, r, client, repo)
+						}
+
+						repos = append(repos, gr)
 					}
 
-					if include[r.Name] {
-						if r.RepositoryAccessLevel != gitlab.DisabledAccessControl {
+					if r.WikiEnabled && repo.Wiki {
+						if activeWiki(r, client, repo) {
+							httpURLToRepo := types.DotGitRx.ReplaceAllString(r.HTTPURLToRepo, ".wiki.git")
+							sshURLToRepo := types.DotGitRx.ReplaceAllString(r.SSHURLToRepo, ".wiki.git")
 							repos = append(repos, types.Repo{
-								Name:          r.Path,
-								URL:           r.HTTPURLToRepo,
-								SSHURL:        r.SSHURLToRepo,
+								Name:          r.Path + ".wiki",
+								URL:           httpURLToRepo,
+								SSHURL:        sshURLToRepo,
 								Token:         token,
 								Defaultbranch: r.DefaultBranch,
 								Origin:        repo,
@@ -406,70 +386,6 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 								Private:       r.Visibility == gitlab.PrivateVisibility,
 							})
 						}
-
-						if r.WikiEnabled && repo.Wiki {
-							if activeWiki(r, client, repo) {
-								httpURLToRepo := types.DotGitRx.ReplaceAllString(r.HTTPURLToRepo, ".wiki.git")
-								sshURLToRepo := types.DotGitRx.ReplaceAllString(r.SSHURLToRepo, ".wiki.git")
-								repos = append(repos, types.Repo{
-									Name:          r.Path + ".wiki",
-									URL:           httpURLToRepo,
-									SSHURL:        sshURLToRepo,
-									Token:         token,
-									Defaultbranch: r.DefaultBranch,
-									Origin:        repo,
-									Owner:         k,
-									Hoster:        types.GetHost(repo.URL),
-									Description:   r.Description,
-									Private:       r.Visibility == gitlab.PrivateVisibility,
-								})
-							}
-						}
-
-						continue
-					}
-					if exclude[r.Name] {
-						continue
-					}
-					if excludeorgs[r.Namespace.FullPath] {
-						continue
-					}
-					if len(include) == 0 {
-						if len(includeorgs) == 0 || includeorgs[r.Namespace.FullPath] {
-							if r.RepositoryAccessLevel != gitlab.DisabledAccessControl {
-								repos = append(repos, types.Repo{
-									Name:          r.Path,
-									URL:           r.HTTPURLToRepo,
-									SSHURL:        r.SSHURLToRepo,
-									Token:         token,
-									Defaultbranch: r.DefaultBranch,
-									Origin:        repo,
-									Owner:         k,
-									Hoster:        types.GetHost(repo.URL),
-									Description:   r.Description,
-									Private:       r.Visibility == gitlab.PrivateVisibility,
-								})
-							}
-
-							if r.WikiEnabled && repo.Wiki {
-								if activeWiki(r, client, repo) {
-									httpURLToRepo := types.DotGitRx.ReplaceAllString(r.HTTPURLToRepo, ".wiki.git")
-									sshURLToRepo := types.DotGitRx.ReplaceAllString(r.SSHURLToRepo, ".wiki.git")
-									repos = append(repos, types.Repo{
-										Name:          r.Path + ".wiki",
-										URL:           httpURLToRepo,
-										SSHURL:        sshURLToRepo,
-										Token:         token,
-										Defaultbranch: r.DefaultBranch,
-										Origin:        repo,
-										Owner:         k,
-										Hoster:        types.GetHost(repo.URL),
-										Description:   r.Description,
-										Private:       r.Visibility == gitlab.PrivateVisibility,
-									})
-								}
-							}
-						}
 					}
 				}
 			}
@@ -479,6 +395,113 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 	return repos, ran
 }
 
+// projectMeta returns the metadata of the project the filters are evaluated against,
+// the languages and statistics are only requested if the filter needs them.
+func projectMeta(r *gitlab.Project, client *gitlab.Client, repo types.GenRepo) (types.RepoMeta, error) {
+	meta := types.RepoMeta{
+		Hoster:     types.GetHost(repo.URL),
+		Name:       r.Name,
+		Owner:      r.Namespace.FullPath,
+		FullPath:   r.PathWithNamespace,
+		Stars:      r.StarCount,
+		Topics:     append(r.Topics, r.TagList...),
+		Visibility: string(r.Visibility),
+		Archived:   r.Archived,
+		Fork:       r.ForkedFromProject != nil,
+		Mirror:     r.Mirror,
+		Empty:      r.EmptyRepo,
+	}
+
+	if r.ForkedFromProject != nil {
+		meta.AheadOfParent = aheadOfParent(r, client)
+	}
+
+	if r.CreatedAt != nil {
+		meta.Created = *r.CreatedAt
+	}
+
+	if r.LastActivityAt != nil {
+		meta.LastActivity = *r.LastActivityAt
+	}
+
+	if r.Statistics == nil && repo.Filter.NeedsSize() {
+		project, _, err := client.Projects.GetProject(r.ID, &gitlab.GetProjectOptions{Statistics: gitlab.Bool(true)})
+		if err != nil {
+			return meta, err
+		}
+
+		r.Statistics = project.Statistics
+	}
+
+	if r.Statistics != nil {
+		meta.Size = r.Statistics.RepositorySize
+	}
+
+	if repo.Filter.NeedsLanguage() {
+		langs, _, err := client.Projects.GetProjectLanguages(r.ID)
+		if err != nil {
+			return meta, err
+		}
+
+		percentage := float32(0)
+		for lang, percent := range *langs {
+			if percent > percentage {
+				meta.Language = lang
+				percentage = percent
+			}
+		}
+	}
+
+	return meta, nil
+}
+
+// aheadOfParent counts the commits on the default branch of the fork r which aren't in the default branch of its parent.
+func aheadOfParent(r *gitlab.Project, client *gitlab.Client) func() (int, error) {
+	return func() (int, error) {
+		parent, _, err := client.Projects.GetProject(r.ForkedFromProject.ID, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		compare, _, err := client.Repositories.Compare(r.ID, &gitlab.CompareOptions{
+			From: gitlab.String(parent.DefaultBranch),
+			To:   gitlab.String(r.DefaultBranch),
+		}, fromProject(parent.ID))
+		if err != nil {
+			return 0, err
+		}
+
+		return len(compare.Commits), nil
+	}
+}
+
+// fromProject makes Compare take from of the project id, go-gitlab doesn't support from_project_id.
+func fromProject(id int) gitlab.RequestOptionFunc {
+	return func(req *retryablehttp.Request) error {
+		query := req.URL.Query()
+		query.Set("from_project_id", strconv.Itoa(id))
+		req.URL.RawQuery = query.Encode()
+
+		return nil
+	}
+}
+
+// addParent sets the clone urls of the parent of the fork r.
+func addParent(gr *types.Repo, r *gitlab.Project, client *gitlab.Client, repo types.GenRepo) {
+	parent, _, err := client.Projects.GetProject(r.ForkedFromProject.ID, nil)
+	if err != nil {
+		log.Error().
+			Str("stage", "gitlab").
+			Str("url", repo.URL).
+			Msg(err.Error())
+
+		return
+	}
+
+	gr.ParentURL = parent.HTTPURLToRepo
+	gr.ParentSSHURL = parent.SSHURLToRepo
+}
+
 func activeWiki(r *gitlab.Project, client *gitlab.Client, repo types.GenRepo) bool {
 	wikilistoptions := &gitlab.ListWikisOptions{
 		WithContent: gitlab.Bool(true),
@@ -1,11 +1,13 @@
 package gogs
 
 import (
-	"time"
-
+	"github.com/cooperspencer/gickup/metrics/prometheus"
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/tracing"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/gogs/go-gogs-client"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func getRepoVisibility(visibility string, private bool) bool {
@@ -27,7 +29,17 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		Str("url", d.URL).
 		Msgf("mirroring %s to %s", types.Blue(r.Name), d.URL)
 
-	gogsclient := gogs.NewClient(d.URL, d.GetToken())
+	token, err := d.GetToken()
+	if err != nil {
+		log.Error().
+			Str("stage", "gogs").
+			Str("url", d.URL).
+			Msg(err.Error())
+		return false
+	}
+
+	gogsclient := gogs.NewClient(d.URL, token)
+	gogsclient.SetHTTPClient(prometheus.Instrument("gogs", nil))
 
 	user, err := gogsclient.GetSelfInfo()
 	if err != nil {
@@ -42,10 +54,19 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		d.User = r.Owner
 	}
 
+	destination := "gogs " + d.URL
+
 	if d.User != "" {
 		user, err = gogsclient.GetUserInfo(d.User)
 		if err != nil {
 			if d.CreateOrg {
+				if dry {
+					plan.Add(r.Step(destination, plan.CreateOrg, d.User))
+					plan.Add(r.Step(destination, plan.CreateMirror, ""))
+
+					return true
+				}
+
 				org, err := gogsclient.CreateOrg(gogs.CreateOrgOption{
 					UserName: d.User,
 				})
@@ -68,12 +89,14 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		}
 	}
 
-	if dry {
-		return true
-	}
-
 	repo, err := gogsclient.GetRepo(user.UserName, r.Name)
 	if err != nil {
+		if dry {
+			plan.Add(r.Step(destination, plan.CreateMirror, ""))
+
+			return true
+		}
+
 		opts := gogs.MigrateRepoOption{
 			RepoName:     r.Name,
 			UID:          int(user.ID),
@@ -97,7 +120,9 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 			}
 		}
 
+		span := tracing.Start("gogs migrate", attribute.String("repo", r.Name), attribute.String("url", d.URL))
 		_, err := gogsclient.MigrateRepo(opts)
+		span.End(err)
 		if err != nil {
 			log.Error().
 				Str("stage", "gogs").
@@ -109,13 +134,19 @@ func Backup(r types.Repo, d types.GenRepo, dry bool) bool {
 		return true
 	}
 
-	if repo.Mirror {
+	if !repo.Mirror {
+		plan.Add(r.Step(destination, plan.Skip, "exists and isn't a mirror"))
+	} else if dry {
+		plan.Add(r.Step(destination, plan.Sync, ""))
+	} else {
 		log.Info().
 			Str("stage", "gogs").
 			Str("url", d.URL).
 			Msgf("mirror of %s already exists, syncing instead", types.Blue(r.Name))
 
+		span := tracing.Start("gogs mirror sync", attribute.String("repo", r.Name), attribute.String("url", d.URL))
 		err := gogsclient.MirrorSync(user.UserName, repo.Name)
+		span.End(err)
 		if err != nil {
 			log.Error().
 				Str("stage", "gogs").
@@ -158,8 +189,17 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				Msgf("grabbing repositories from %s", repo.User)
 		}
 
-		token := repo.GetToken()
+		token, err := repo.GetToken()
+		if err != nil {
+			log.Error().
+				Str("stage", "gogs").
+				Str("url", repo.URL).
+				Msg(err.Error())
+			continue
+		}
+
 		client := gogs.NewClient(repo.URL, token)
+		client.SetHTTPClient(prometheus.Instrument("gogs", nil))
 		var gogsrepos []*gogs.Repository
 
 		if repo.User == "" {
@@ -175,82 +215,7 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 			continue
 		}
 
-		include := types.GetMap(repo.Include)
-		includeorgs := types.GetMap(repo.IncludeOrgs)
-		exclude := types.GetMap(repo.Exclude)
-		excludeorgs := types.GetMap(repo.ExcludeOrgs)
-
-		for _, r := range gogsrepos {
-			if r.Stars < repo.Filter.Stars {
-				continue
-			}
-			if time.Since(r.Updated) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-				continue
-			}
-			if include[r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
-					Token:         token,
-					Defaultbranch: r.DefaultBranch,
-					Origin:        repo,
-					Owner:         r.Owner.UserName,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Private,
-				})
-				if repo.Wiki {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
-
-				continue
-			}
-
-			if exclude[r.Name] {
-				continue
-			}
-
-			if len(include) == 0 {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
-					Token:         token,
-					Defaultbranch: r.DefaultBranch,
-					Origin:        repo,
-					Owner:         r.Owner.UserName,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Private,
-				})
-				if repo.Wiki {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
-			}
-		}
+		sel := repo.Selector()
 
 		var orgs []*gogs.Organization
 
@@ -266,93 +231,67 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				Msg(err.Error())
 		}
 
-		orgrepos := []*gogs.Repository{}
 		for _, org := range orgs {
-			if excludeorgs[org.UserName] {
+			if !sel.OrgSelected(org.UserName) {
 				continue
 			}
-			for {
-				if len(includeorgs) > 0 {
-					if includeorgs[org.UserName] {
-						o, err := client.ListOrgRepos(org.UserName)
-						if err != nil {
-							log.Error().
-								Str("stage", "gogs").
-								Str("url", repo.URL).
-								Msg(err.Error())
-						}
-
-						if len(o) == 0 {
-							break
-						}
-
-						orgrepos = append(orgrepos, o...)
-					}
-				} else {
-					o, err := client.ListOrgRepos(org.UserName)
-					if err != nil {
-						log.Error().
-							Str("stage", "gogs").
-							Str("url", repo.URL).
-							Msg(err.Error())
-					}
-
-					if len(o) == 0 {
-						break
-					}
-
-					orgrepos = append(orgrepos, o...)
-				}
+
+			o, err := client.ListOrgRepos(org.UserName)
+			if err != nil {
+				log.Error().
+					Str("stage", "gogs").
+					Str("url", repo.URL).
+					Msg(err.Error())
 			}
+
+			gogsrepos = append(gogsrepos, o...)
 		}
-		for _, r := range orgrepos {
-			if r.Stars < repo.Filter.Stars {
-				continue
+
+		for _, r := range gogsrepos {
+			meta := types.RepoMeta{
+				Hoster:       types.GetHost(repo.URL),
+				Name:         r.Name,
+				Owner:        r.Owner.UserName,
+				FullPath:     r.FullName,
+				Stars:        r.Stars,
+				Visibility:   types.VisibilityOf(r.Private),
+				Fork:         r.Fork,
+				Mirror:       r.Mirror,
+				Empty:        r.Empty,
+				Size:         r.Size,
+				Created:      r.Created,
+				LastActivity: r.Updated,
 			}
-			if time.Since(r.Updated) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
+
+			if !repo.Accept("gogs", sel, meta) {
 				continue
 			}
 
-			if include[r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
-					Token:         token,
-					Defaultbranch: r.DefaultBranch,
-					Origin:        repo,
-					Owner:         r.Owner.UserName,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Private,
-				})
-				if repo.Wiki {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
-
-				continue
+			gr := types.Repo{
+				Name:          r.Name,
+				URL:           r.CloneURL,
+				SSHURL:        r.SSHURL,
+				Token:         token,
+				Defaultbranch: r.DefaultBranch,
+				Origin:        repo,
+				Owner:         r.Owner.UserName,
+				Hoster:        types.GetHost(repo.URL),
+				Description:   r.Description,
+				Private:       r.Private,
+				Size:          meta.Size,
 			}
 
-			if exclude[r.Name] {
-				continue
+			if repo.ParentRefs && r.Parent != nil {
+				gr.ParentURL = r.Parent.CloneURL
+				gr.ParentSSHURL = r.Parent.SSHURL
 			}
 
-			if len(repo.Include) == 0 {
+			repos = append(repos, gr)
+			if repo.Wiki {
 				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           r.CloneURL,
-					SSHURL:        r.SSHURL,
+					Name:          r.Name + ".wiki",
+					URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
+					SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
 					Token:         token,
 					Defaultbranch: r.DefaultBranch,
 					Origin:        repo,
@@ -361,21 +300,6 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 					Description:   r.Description,
 					Private:       r.Private,
 				})
-
-				if repo.Wiki {
-					repos = append(repos, types.Repo{
-						Name:          r.Name + ".wiki",
-						URL:           types.DotGitRx.ReplaceAllString(r.CloneURL, ".wiki.git"),
-						SSHURL:        types.DotGitRx.ReplaceAllString(r.SSHURL, ".wiki.git"),
-						Token:         token,
-						Defaultbranch: r.DefaultBranch,
-						Origin:        repo,
-						Owner:         r.Owner.UserName,
-						Hoster:        types.GetHost(repo.URL),
-						Description:   r.Description,
-						Private:       r.Private,
-					})
-				}
 			}
 		}
 	}
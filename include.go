@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth stops includes which include each other.
+const maxIncludeDepth = 10
+
+// mappingValue returns the value of key in the mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// documentRoot returns the mapping of a document node.
+func documentRoot(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+
+	return node
+}
+
+func copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	c.Content = make([]*yaml.Node, len(node.Content))
+	for i, n := range node.Content {
+		c.Content[i] = copyNode(n)
+	}
+
+	return &c
+}
+
+// mergeNodes merges src into dst, values of dst win, mappings are merged and sequences appended.
+func mergeNodes(dst, src *yaml.Node) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			existing := mappingValue(dst, key.Value)
+			if existing == nil {
+				dst.Content = append(dst.Content, copyNode(key), copyNode(value))
+				continue
+			}
+
+			mergeNodes(existing, value)
+		}
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for _, n := range src.Content {
+			dst.Content = append(dst.Content, copyNode(n))
+		}
+	}
+}
+
+// resolveIncludes merges the files and glob patterns listed in include into the document,
+// relative paths are resolved from dir.
+func resolveIncludes(node *yaml.Node, dir string, depth int) error {
+	root := documentRoot(node)
+	include := mappingValue(root, "include")
+	if include == nil {
+		return nil
+	}
+
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("includes are nested too deep, do they include each other?")
+	}
+
+	patterns := []string{}
+	switch include.Kind {
+	case yaml.SequenceNode:
+		for _, n := range include.Content {
+			patterns = append(patterns, n.Value)
+		}
+	case yaml.ScalarNode:
+		patterns = append(patterns, include.Value)
+
+		// a single file is decoded like a list of files
+		*include = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: include.Line, Column: include.Column, Content: []*yaml.Node{copyNode(include)}}
+	default:
+		return fmt.Errorf("line %d: include has to be a file or a list of files", include.Line)
+	}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("include %s: %s", pattern, err.Error())
+		}
+
+		if len(files) == 0 {
+			return fmt.Errorf("include %s: no such file", pattern)
+		}
+
+		sort.Strings(files)
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("include %s: %s", file, err.Error())
+			}
+
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			for {
+				included := yaml.Node{}
+				err := dec.Decode(&included)
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return fmt.Errorf("include %s: %s", file, err.Error())
+				}
+
+				if err := resolveIncludes(&included, filepath.Dir(file), depth+1); err != nil {
+					return err
+				}
+
+				if documentRoot(&included).Kind == yaml.MappingNode {
+					mergeNodes(root, documentRoot(&included))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// collectDefaults adds the templates of the document to defaults.
+func collectDefaults(node *yaml.Node, defaults map[string]*yaml.Node) {
+	templates := mappingValue(documentRoot(node), "defaults")
+	if templates == nil || templates.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(templates.Content); i += 2 {
+		defaults[templates.Content[i].Value] = templates.Content[i+1]
+	}
+}
+
+// extend merges the template entry extends into entry, following templates which extend other templates.
+func extend(entry *yaml.Node, defaults map[string]*yaml.Node, seen map[string]bool) error {
+	name := mappingValue(entry, "extends")
+	if name == nil || name.Value == "" {
+		return nil
+	}
+
+	if seen[name.Value] {
+		return fmt.Errorf("line %d: template %s extends itself", name.Line, name.Value)
+	}
+	seen[name.Value] = true
+
+	template, ok := defaults[name.Value]
+	if !ok {
+		return fmt.Errorf("line %d: template %s is not defined in defaults", name.Line, name.Value)
+	}
+
+	template = copyNode(template)
+	if err := extend(template, defaults, seen); err != nil {
+		return err
+	}
+
+	mergeNodes(entry, template)
+
+	return nil
+}
+
+// resolveExtends merges the templates into every source and destination which extends one.
+func resolveExtends(node *yaml.Node, defaults map[string]*yaml.Node) error {
+	root := documentRoot(node)
+
+	for _, section := range []string{"source", "destination"} {
+		hosters := mappingValue(root, section)
+		if hosters == nil || hosters.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for i := 1; i < len(hosters.Content); i += 2 {
+			for _, entry := range hosters.Content[i].Content {
+				// the templates are sources, which local destinations have nothing in common with
+				if section == "destination" && hosters.Content[i-1].Value == "local" {
+					if name := mappingValue(entry, "extends"); name != nil {
+						return fmt.Errorf("line %d: local destinations can't extend templates", name.Line)
+					}
+
+					continue
+				}
+
+				if err := extend(entry, defaults, map[string]bool{}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog/log"
+)
+
+// listed is a repository as printed by gickup list.
+type listed struct {
+	Hoster        string `json:"hoster"`
+	Owner         string `json:"owner"`
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+	Wiki          bool   `json:"wiki"`
+	Reason        string `json:"reason"`
+}
+
+// list prints the repositories the sources of the configfiles discover, source restricts them to one hoster
+// or one source entry, e.g. github:1 or github:some-user.
+func list(configfiles []string, source, format string, skipped bool) int {
+	if skipped {
+		plan.Enable()
+	}
+
+	hoster, entry := source, ""
+	if parts := strings.SplitN(source, ":", 2); len(parts) == 2 {
+		hoster, entry = parts[0], parts[1]
+	}
+
+	rows := []listed{}
+	for _, f := range configfiles {
+		confs, err := loadConfigFile(f, false)
+		if err != nil {
+			log.Error().
+				Str("stage", "list").
+				Str("file", f).
+				Msg(err.Error())
+
+			return 1
+		}
+
+		for _, conf := range confs {
+			if source != "" {
+				only, err := conf.Source.Only(hoster, entry)
+				if err != nil {
+					log.Error().
+						Str("stage", "list").
+						Str("file", f).
+						Msg(err.Error())
+
+					return 1
+				}
+
+				conf.Source = only
+			}
+
+			for _, s := range sources {
+				repos, _ := s.get(conf)
+				for _, r := range repos {
+					rows = append(rows, listedRepo(r))
+				}
+			}
+		}
+	}
+
+	for _, step := range plan.Take() {
+		owner, name := "", step.Repo
+		if i := strings.LastIndex(step.Repo, "/"); i >= 0 {
+			owner, name = step.Repo[:i], step.Repo[i+1:]
+		}
+
+		rows = append(rows, listed{Hoster: step.Source, Owner: owner, Name: name, Reason: "skipped, " + step.Reason})
+	}
+
+	if err := writeListed(os.Stdout, rows, format); err != nil {
+		log.Error().
+			Str("stage", "list").
+			Msg(err.Error())
+
+		return 1
+	}
+
+	return 0
+}
+
+func listedRepo(r types.Repo) listed {
+	reason := r.Origin.Selector().Reason(r.Name, r.Owner, "")
+	if r.Origin.Filter.IsSet() {
+		reason += ", matches the filter"
+	}
+
+	return listed{
+		Hoster:        r.Hoster,
+		Owner:         r.Owner,
+		Name:          r.Name,
+		URL:           r.URL,
+		Private:       r.Private,
+		DefaultBranch: r.Defaultbranch,
+		Wiki:          strings.HasSuffix(r.Name, ".wiki"),
+		Reason:        reason,
+	}
+}
+
+func writeListed(w io.Writer, rows []listed, format string) error {
+	header := []string{"HOSTER", "OWNER", "NAME", "URL", "PRIVATE", "DEFAULT BRANCH", "WIKI", "REASON"}
+	record := func(r listed) []string {
+		return []string{r.Hoster, r.Owner, r.Name, r.URL, strconv.FormatBool(r.Private), r.DefaultBranch, strconv.FormatBool(r.Wiki), r.Reason}
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(rows)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+
+		for _, r := range rows {
+			if err := cw.Write(record(r)); err != nil {
+				return err
+			}
+		}
+
+		cw.Flush()
+
+		return cw.Error()
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+		for _, r := range rows {
+			fmt.Fprintln(tw, strings.Join(record(r), "\t"))
+		}
+
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s, use table, json or csv", format)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/tracing"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
@@ -21,12 +23,38 @@ import (
 	"github.com/melbahja/goph"
 	"github.com/mholt/archiver/v4"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 	gossh "golang.org/x/crypto/ssh"
 )
 
+// RepoPath returns the path where repo is stored in l, without the timestamp used for kept backups.
+func RepoPath(repo types.Repo, l types.Local) string {
+	name := repo.Name
+	if l.Structured {
+		name = path.Join(repo.Hoster, repo.Owner, name)
+	}
+
+	if l.Bare {
+		name += ".git"
+	}
+
+	return path.Join(l.Path, name)
+}
+
+// ArchiveSuffix returns the suffix of the archives created for l, or an empty string if it isn't compressed.
+func ArchiveSuffix(l types.Local) string {
+	if l.Compression == "" {
+		return ""
+	}
+
+	return getCompressedArchiveSuffix(l.Compression)
+}
+
 // Locally TODO.
 func Locally(repo types.Repo, l types.Local, dry bool) bool {
 	date := time.Now()
+	origin := repo
+	destination := "local " + l.Path
 
 	if l.Structured {
 		repo.Name = path.Join(repo.Hoster, repo.Owner, repo.Name)
@@ -53,6 +81,7 @@ func Locally(repo types.Repo, l types.Local, dry bool) bool {
 		stat, _ = os.Stat(l.Path)
 	}
 
+	inPath := false
 	if stat != nil && stat.IsDir() {
 		if err := os.Chdir(l.Path); err != nil {
 			log.Error().
@@ -61,6 +90,8 @@ func Locally(repo types.Repo, l types.Local, dry bool) bool {
 				Msg(err.Error())
 			return false
 		}
+
+		inPath = true
 	}
 
 	tries := 5
@@ -96,47 +127,64 @@ func Locally(repo types.Repo, l types.Local, dry bool) bool {
 
 	for x := 1; x <= tries; x++ {
 		stat, err := os.Stat(repo.Name)
+		if !inPath {
+			// a dry-run doesn't create the path, so nothing has been backed up yet
+			stat, err = nil, os.ErrNotExist
+		}
+
 		if os.IsNotExist(err) {
+			if err := checkFreeSpace(repo, l); err != nil {
+				log.Error().
+					Str("stage", "locally").
+					Str("path", l.Path).
+					Msg(err.Error())
+				plan.Add(origin.Step(destination, plan.Skip, err.Error()))
+
+				return false
+			}
+
+			if dry {
+				plan.Add(origin.Step(destination, plan.Clone, ""))
+			}
+
 			log.Info().
 				Str("stage", "locally").
 				Str("path", l.Path).
 				Msgf("cloning %s", types.Green(repo.Name))
 
+			span := tracing.Start("git clone", attribute.String("repo", repo.Name), attribute.Int("try", x))
 			err := cloneRepository(repo, auth, dry, l.Bare)
+			span.End(err)
 			if err != nil {
 				if err.Error() == "repository not found" {
-					log.Warn().
+					log.Error().
 						Str("stage", "locally").
 						Str("path", l.Path).
-						Str("repo", repo.Name).
 						Msg(err.Error())
-					break
+					return false
 				}
 				if x == tries {
-					log.Warn().
+					log.Error().
 						Str("stage", "locally").
 						Str("path", l.Path).
-						Str("repo", repo.Name).
 						Msg(err.Error())
 
-					break
+					return false
 				}
 
 				if strings.Contains(err.Error(), "ERR access denied or repository not exported") {
-					log.Warn().
+					log.Error().
 						Str("stage", "locally").
 						Str("path", l.Path).
-						Str("repo", repo.Name).
 						Msgf("%s doesn't exist.", repo.Name)
 
-					break
+					return false
 				}
 
 				if strings.Contains(err.Error(), "remote repository is empty") {
 					log.Warn().
 						Str("stage", "locally").
 						Str("path", l.Path).
-						Str("repo", repo.Name).
 						Msg(err.Error())
 
 					break
@@ -156,15 +204,25 @@ func Locally(repo types.Repo, l types.Local, dry bool) bool {
 				log.Warn().
 					Str("stage", "locally").
 					Str("path", l.Path).
-					Str("repo", repo.Name).
 					Msgf("%s is a file", types.Red(repo.Name))
+				plan.Add(origin.Step(destination, plan.Skip, repo.Name+" is a file"))
 			} else {
+				if dry {
+					plan.Add(origin.Step(destination, plan.Fetch, ""))
+				}
+
 				log.Info().
 					Str("stage", "locally").
 					Str("path", l.Path).
 					Msgf("opening %s locally", types.Green(repo.Name))
 
+				span := tracing.Start("git fetch", attribute.String("repo", repo.Name), attribute.Int("try", x))
 				err := updateRepository(repo.Name, auth, dry, l.Bare)
+				if err == git.NoErrAlreadyUpToDate {
+					span.End(nil)
+				} else {
+					span.End(err)
+				}
 				if err != nil {
 					if strings.Contains(err.Error(), "already up-to-date") {
 						log.Info().
@@ -173,17 +231,17 @@ func Locally(repo types.Repo, l types.Local, dry bool) bool {
 							Msg(err.Error())
 					} else {
 						if x == tries {
-							log.Fatal().
+							log.Error().
 								Str("stage", "locally").
 								Str("path", l.Path).
-								Str("repo", repo.Name).
 								Msg(err.Error())
+
+							return false
 						} else {
 							os.RemoveAll(repo.Name)
 							log.Warn().
 								Str("stage", "locally").
 								Str("path", l.Path).
-								Str("repo", repo.Name).
 								Msgf("retry %s from %s", types.Red(x), types.Red(tries))
 
 							time.Sleep(5 * time.Second)
@@ -194,106 +252,204 @@ func Locally(repo types.Repo, l types.Local, dry bool) bool {
 				}
 			}
 		}
-		if l.Compression != "" {
-			file_suffix := getCompressedArchiveSuffix(l.Compression)
+		if repo.Origin.ParentRefs && repo.ParentURL != "" {
+			parentURL := repo.ParentURL
+			if repo.Origin.SSH {
+				parentURL = repo.ParentSSHURL
+			}
 
 			log.Info().
 				Str("stage", "locally").
 				Str("path", l.Path).
-				Msgf("compressing %s", types.Green(repo.Name))
+				Msgf("fetching the parent of %s", types.Green(repo.Name))
 
-			files, err := archiver.FilesFromDisk(nil, map[string]string{
-				repo.Name: "", // contents added recursively
-			})
-			if err != nil {
-				log.Warn().
-					Str("stage", "locally").
-					Str("path", l.Path).
-					Str("repo", repo.Name).Msg(err.Error())
+			if dry {
+				plan.Add(origin.Step(destination, plan.Fetch, "refs of the parent"))
 			}
-			out, err := os.Create(fmt.Sprintf("%s%s", repo.Name, file_suffix))
-			if err != nil {
-				log.Warn().
-					Str("stage", "locally").
-					Str("path", l.Path).
-					Str("repo", repo.Name).Msg(err.Error())
-			}
-			defer out.Close()
 
-			archiver_fmt := getArchiverFmt(l.Compression)
+			span := tracing.Start("git fetch parent", attribute.String("repo", repo.Name))
+			err := fetchParentRefs(repo.Name, parentURL, auth, dry)
+			span.End(err)
 
-			err = archiver_fmt.Archive(context.Background(), out, files)
 			if err != nil {
 				log.Warn().
 					Str("stage", "locally").
 					Str("path", l.Path).
-					Str("repo", repo.Name).Msg(err.Error())
+					Msg(err.Error())
 			}
+		}
 
-			err = os.RemoveAll(repo.Name)
+		if l.Compression != "" && !dry {
+			span := tracing.Start("archive", attribute.String("repo", repo.Name), attribute.String("compression", l.Compression))
+			err := compress(repo, l)
+			span.End(err)
 			if err != nil {
-				log.Warn().
-					Str("stage", "locally").
-					Str("path", l.Path).
-					Str("repo", repo.Name).Msg(err.Error())
+				return false
 			}
 		}
 
 		if l.Keep > 0 {
-			parentdir := path.Dir(repo.Name)
-			files, err := ioutil.ReadDir(parentdir)
+			span := tracing.Start("prune", attribute.String("repo", repo.Name), attribute.Int("keep", l.Keep))
+			span.End(prune(repo, origin, l, destination, dry))
+		}
+
+		x = 5
+	}
+	return true
+}
+
+// compress archives the snapshot of repo and removes its directory.
+func compress(repo types.Repo, l types.Local) error {
+	file_suffix := getCompressedArchiveSuffix(l.Compression)
+
+	log.Info().
+		Str("stage", "locally").
+		Str("path", l.Path).
+		Msgf("compressing %s", types.Green(repo.Name))
+
+	files, err := archiver.FilesFromDisk(nil, map[string]string{
+		repo.Name: "", // contents added recursively
+	})
+	if err != nil {
+		log.Error().
+			Str("stage", "locally").
+			Str("path", l.Path).
+			Msg(err.Error())
+		return err
+	}
+	archive := fmt.Sprintf("%s%s", repo.Name, file_suffix)
+	out, err := os.Create(archive)
+	if err != nil {
+		log.Error().
+			Str("stage", "locally").
+			Str("path", l.Path).
+			Msg(err.Error())
+		return err
+	}
+	defer out.Close()
+
+	archiver_fmt := getArchiverFmt(l.Compression)
+
+	archiveErr := archiver_fmt.Archive(context.Background(), out, files)
+	if archiveErr != nil {
+		log.Error().
+			Str("stage", "locally").
+			Str("path", l.Path).
+			Msg(archiveErr.Error())
+		// the snapshot is kept instead of the incomplete archive
+		out.Close()
+		os.Remove(archive)
+		return archiveErr
+	}
+
+	err = os.RemoveAll(repo.Name)
+	if err != nil {
+		log.Warn().
+			Str("stage", "locally").
+			Str("path", l.Path).
+			Msg(err.Error())
+	}
+
+	return archiveErr
+}
+
+// prune removes the oldest snapshots of repo, keeping l.Keep of them.
+func prune(repo, origin types.Repo, l types.Local, destination string, dry bool) error {
+	parentdir := path.Dir(repo.Name)
+	files, err := ioutil.ReadDir(parentdir)
+	if dry && os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		log.Warn().
+			Str("stage", "locally").
+			Str("path", l.Path).
+			Msg(err.Error())
+		return err
+	}
+	file_suffix := getCompressedArchiveSuffix(l.Compression)
+
+	keep := []string{}
+	for _, file := range files {
+		fname := file.Name()
+		if l.Compression != "" {
+			fname = strings.TrimSuffix(file.Name(), file_suffix)
+		}
+		_, err := strconv.ParseInt(fname, 10, 64)
+		if err != nil {
+			log.Warn().
+				Str("stage", "locally").
+				Str("path", l.Path).
+				Msgf("couldn't parse timestamp! %s", types.Red(file.Name()))
+		}
+		if l.Compression != "" && !strings.HasSuffix(file.Name(), file_suffix) {
+			continue
+		}
+		keep = append(keep, file.Name())
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(keep)))
+
+	// a dry-run doesn't create the new snapshot, which would count too
+	limit := l.Keep
+	if dry {
+		limit--
+	}
+
+	if len(keep) > limit {
+		toremove := keep[limit:]
+		for _, file := range toremove {
+			if dry {
+				plan.Add(origin.Step(destination, plan.Prune, path.Join(parentdir, file)))
+				continue
+			}
+
+			log.Info().
+				Str("stage", "locally").
+				Str("path", l.Path).
+				Msgf("removing %s", types.Red(path.Join(parentdir, file)))
+			err := os.RemoveAll(path.Join(parentdir, file))
 			if err != nil {
 				log.Warn().
 					Str("stage", "locally").
 					Str("path", l.Path).
-					Str("repo", repo.Name).Msg(err.Error())
-				break
+					Msg(err.Error())
 			}
-			file_suffix := getCompressedArchiveSuffix(l.Compression)
+		}
+	}
 
-			keep := []string{}
-			for _, file := range files {
-				fname := file.Name()
-				if l.Compression != "" {
-					fname = strings.TrimSuffix(file.Name(), file_suffix)
-				}
-				_, err := strconv.ParseInt(fname, 10, 64)
-				if err != nil {
-					log.Warn().
-						Str("stage", "locally").
-						Str("path", l.Path).
-						Str("repo", repo.Name).
-						Msgf("couldn't parse timestamp! %s", types.Red(file.Name()))
-				}
-				if l.Compression != "" && !strings.HasSuffix(file.Name(), file_suffix) {
-					continue
-				}
-				keep = append(keep, file.Name())
-			}
+	return nil
+}
 
-			sort.Sort(sort.Reverse(sort.StringSlice(keep)))
+// checkFreeSpace checks if the repository fits on the filesystem of the destination, keeping minfree free.
+// Without the size of the repository, only minfree is checked.
+func checkFreeSpace(repo types.Repo, l types.Local) error {
+	minfree := int64(0)
+	if l.MinFree != "" {
+		minfree, _ = types.ParseSize(l.MinFree)
+	}
 
-			if len(keep) > l.Keep {
-				toremove := keep[l.Keep:]
-				for _, file := range toremove {
-					log.Info().
-						Str("stage", "locally").
-						Str("path", l.Path).
-						Msgf("removing %s", types.Red(path.Join(parentdir, file)))
-					err := os.RemoveAll(path.Join(parentdir, file))
-					if err != nil {
-						log.Warn().
-							Str("stage", "locally").
-							Str("path", l.Path).
-							Str("repo", repo.Name).Msg(err.Error())
-					}
-				}
-			}
-		}
+	if repo.Size == 0 && minfree == 0 {
+		return nil
+	}
 
-		x = 5
+	free, err := freeSpace(l.Path)
+	if err != nil {
+		log.Debug().
+			Str("stage", "locally").
+			Str("path", l.Path).
+			Msgf("can't check the free space: %s", err.Error())
+
+		return nil
 	}
-	return true
+
+	if uint64(repo.Size+minfree) > free {
+		return fmt.Errorf("not enough space to clone %s, %s needed, %s free",
+			repo.Name, types.FormatSize(repo.Size+minfree), types.FormatSize(int64(free)))
+	}
+
+	return nil
 }
 
 func getCompressedArchiveSuffix(compression string) string {
@@ -351,6 +507,46 @@ func updateRepository(repoPath string, auth transport.AuthMethod, dry bool, bare
 	return err
 }
 
+// parentRefSpecs fetch the branches and tags of the parent of a fork into refs/parent, apart from the refs of the fork.
+var parentRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/parent/heads/*",
+	"+refs/tags/*:refs/parent/tags/*",
+}
+
+func fetchParentRefs(repoPath, url string, auth transport.AuthMethod, dry bool) error {
+	if dry {
+		return nil
+	}
+
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return err
+	}
+
+	// the parent may have moved since the last backup
+	if _, err := r.Remote("parent"); err == nil {
+		if err := r.DeleteRemote("parent"); err != nil {
+			return err
+		}
+	}
+
+	remote, err := r.CreateRemote(&config.RemoteConfig{
+		Name:  "parent",
+		URLs:  []string{url},
+		Fetch: parentRefSpecs,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = remote.Fetch(&git.FetchOptions{Auth: auth, RefSpecs: parentRefSpecs, Tags: git.NoTags})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+
+	return err
+}
+
 func cloneRepository(repo types.Repo, auth transport.AuthMethod, dry bool, bare bool) error {
 	if dry {
 		return nil
@@ -363,17 +559,17 @@ func cloneRepository(repo types.Repo, auth transport.AuthMethod, dry bool, bare
 
 		err := site.GetValues(url)
 		if err != nil {
-			log.Fatal().Str("stage", "locally").Str("repo", repo.Name).Msg(err.Error())
+			log.Fatal().Str("stage", "locally").Msg(err.Error())
 		}
 
 		sshAuth, err := goph.Key(repo.Origin.SSHKey, "")
 		if err != nil {
-			log.Fatal().Str("stage", "locally").Str("repo", repo.Name).Msg(err.Error())
+			log.Fatal().Str("stage", "locally").Msg(err.Error())
 		}
 
 		err = testSSHConnection(site, sshAuth)
 		if err != nil {
-			log.Fatal().Str("stage", "locally").Str("repo", repo.Name).Msg(err.Error())
+			log.Fatal().Str("stage", "locally").Msg(err.Error())
 		}
 	}
 
@@ -0,0 +1,90 @@
+package local
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ObjectCount returns the count of the git objects of the repository at repoPath, the objects of the
+// packs and the loose objects. Destinations with keep count the newest snapshot. It returns false if
+// there is no repository to count, e.g. if it is compressed.
+func ObjectCount(repoPath string) (int64, bool) {
+	objects, ok := objectsDir(repoPath)
+	if !ok {
+		return 0, false
+	}
+
+	count := int64(0)
+
+	indexes, _ := filepath.Glob(filepath.Join(objects, "pack", "*.idx"))
+	for _, index := range indexes {
+		count += packObjects(index)
+	}
+
+	dirs, _ := filepath.Glob(filepath.Join(objects, "[0-9a-f][0-9a-f]"))
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			count += int64(len(entries))
+		}
+	}
+
+	return count, true
+}
+
+// objectsDir returns the objects directory of the repository at repoPath or of its newest snapshot.
+func objectsDir(repoPath string) (string, bool) {
+	dirs := []string{filepath.Join(repoPath, ".git", "objects"), filepath.Join(repoPath, "objects")}
+
+	newest := int64(-1)
+	entries, _ := os.ReadDir(repoPath)
+	for _, entry := range entries {
+		timestamp, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err == nil && entry.IsDir() && timestamp > newest {
+			newest = timestamp
+		}
+	}
+
+	if newest >= 0 {
+		snapshot := filepath.Join(repoPath, strconv.FormatInt(newest, 10))
+		dirs = append(dirs, filepath.Join(snapshot, ".git", "objects"), filepath.Join(snapshot, "objects"))
+	}
+
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+
+	return "", false
+}
+
+// packObjects reads the count of objects from the last entry of the fanout table of a pack index.
+func packObjects(index string) int64 {
+	f, err := os.Open(index)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0
+	}
+
+	// version 2 indexes start with a magic number and the version, version 1 with the fanout table
+	offset := int64(255 * 4)
+	if string(header[:4]) == "\377tOc" {
+		offset += 8
+	}
+
+	count := make([]byte, 4)
+	if _, err := f.ReadAt(count, offset); err != nil {
+		return 0
+	}
+
+	return int64(binary.BigEndian.Uint32(count))
+}
@@ -0,0 +1,34 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestObjectCountOfSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"gickup/1700000000/objects/ab/0001",
+		"gickup/1700000100/objects/ab/0001",
+		"gickup/1700000100/objects/cd/0002",
+		"archived/1700000000.tar.gz",
+	} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, ok := ObjectCount(filepath.Join(dir, "gickup")); !ok || count != 2 {
+		t.Errorf("expected the 2 objects of the newest snapshot, got %d, %v", count, ok)
+	}
+
+	if _, ok := ObjectCount(filepath.Join(dir, "archived")); ok {
+		t.Error("objects of a compressed snapshot were counted")
+	}
+}
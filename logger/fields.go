@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// the fields are added in this order
+var fieldNames = []string{"run_id", "hoster", "owner", "repo", "destination"}
+
+var (
+	mu     sync.Mutex
+	fields = map[string]string{}
+)
+
+// NewRun starts a run with a new random id, which is the run_id of the following log lines.
+func NewRun() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	// a version 4 uuid
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	id := fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	fields = map[string]string{"run_id": id}
+
+	return id
+}
+
+// EndRun removes the run and repository fields from the following log lines.
+func EndRun() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fields = map[string]string{}
+}
+
+// Suspend removes the fields from the following log lines and returns them, e.g. while a run waits
+// and another one logs.
+func Suspend() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	suspended := fields
+	fields = map[string]string{}
+
+	return suspended
+}
+
+// Resume restores the suspended fields.
+func Resume(suspended map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fields = suspended
+}
+
+// SetRepo sets the repository and destination fields of the following log lines, the run_id is kept.
+// Backups run one after another, so the fields belong to the running backup.
+func SetRepo(hoster, owner, repo, destination string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fields["hoster"] = hoster
+	fields["owner"] = owner
+	fields["repo"] = repo
+	fields["destination"] = destination
+}
+
+// ClearRepo removes the repository and destination fields from the following log lines.
+func ClearRepo() {
+	SetRepo("", "", "", "")
+}
+
+// fieldHook adds the fields of the run and the repository to the log lines.
+type fieldHook struct{}
+
+func (fieldHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, name := range fieldNames {
+		if value := fields[name]; value != "" {
+			e.Str(name, value)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"os"
 	"path"
+	"regexp"
 
+	"github.com/cooperspencer/gickup/secrets"
+	"github.com/cooperspencer/gickup/status"
+	"github.com/cooperspencer/gickup/tracing"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -36,13 +42,154 @@ func NewRollingFile(config types.FileLogging) io.Writer {
 func CreateLogger(conf types.Logging) zerolog.Logger {
 	var writers []io.Writer
 
-	writers = append(writers, zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: conf.Timeformat})
+	if conf.Format == types.LogJSON {
+		writers = append(writers, filter(stripColors{os.Stderr}, conf.Level))
+	} else {
+		writers = append(writers, filter(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: conf.Timeformat}, conf.Level))
+	}
 
 	if conf.FileLogging.File != "" {
-		writers = append(writers, NewRollingFile(conf.FileLogging))
+		if file := NewRollingFile(conf.FileLogging); file != nil {
+			writers = append(writers, filter(stripColors{file}, conf.FileLogging.Level))
+		}
+	}
+
+	writers = append(writers, secrets.RedactWriter(status.Logs))
+
+	base := zerolog.New(dedupe{zerolog.MultiLevelWriter(writers...)}).With().Timestamp().Logger()
+	background = base
+
+	return base.Hook(tracing.LogHook{}).Hook(fieldHook{})
+}
+
+// background logs without the fields and trace ids of the running backup.
+var background = zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+// Background returns the logger of the goroutines which don't belong to the running backup, e.g. the
+// listeners and the reload of the configuration, their lines would get the fields of the backup otherwise.
+func Background() *zerolog.Logger {
+	return &background
+}
+
+// levelWriter drops the lines below its level.
+type levelWriter struct {
+	w     io.Writer
+	level zerolog.Level
+}
+
+func filter(w io.Writer, level string) levelWriter {
+	l, err := zerolog.ParseLevel(level)
+	if err != nil || level == "" {
+		l = zerolog.TraceLevel
+	}
+
+	return levelWriter{w: secrets.RedactWriter(w), level: l}
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func (w levelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.level {
+		return len(p), nil
+	}
+
+	return w.w.Write(p)
+}
+
+// dedupe drops the fields of the hooks which a log line already has. The hooks add their fields
+// last, so the field set by the line itself is kept.
+type dedupe struct {
+	w zerolog.LevelWriter
+}
+
+func (d dedupe) Write(p []byte) (int, error) {
+	if _, err := d.w.Write(unique(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (d dedupe) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if _, err := d.w.WriteLevel(level, unique(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// hookFields are the fields added by the hooks.
+var hookFields = append([]string{"trace_id", "span_id"}, fieldNames...)
+
+// unique removes the repeated fields of the json log line p, the first one is kept.
+func unique(p []byte) []byte {
+	repeated := false
+	for _, name := range hookFields {
+		if bytes.Count(p, []byte(`"`+name+`":`)) > 1 {
+			repeated = true
+			break
+		}
 	}
 
-	mw := io.MultiWriter(writers...)
+	if !repeated {
+		return p
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return p
+	}
+
+	seen := map[string]bool{}
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return p
+		}
+
+		key, _ := token.(string)
+		value := json.RawMessage{}
+		if err := decoder.Decode(&value); err != nil {
+			return p
+		}
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
+}
+
+// jsonColorRx matches the colour codes of types.Red, types.Green and types.Blue in json strings.
+var jsonColorRx = regexp.MustCompile(`\\u001b\[[0-9;]*m`)
+
+// stripColors removes the colour codes from json log lines.
+type stripColors struct {
+	w io.Writer
+}
+
+func (s stripColors) Write(p []byte) (int, error) {
+	if _, err := s.w.Write(jsonColorRx.ReplaceAll(p, nil)); err != nil {
+		return 0, err
+	}
 
-	return zerolog.New(mw).With().Timestamp().Logger()
+	return len(p), nil
 }
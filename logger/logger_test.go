@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog"
+)
+
+func TestWriters(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := zerolog.New(zerolog.MultiLevelWriter(filter(stripColors{&buf}, "info"))).Hook(fieldHook{})
+
+	id := NewRun()
+	SetRepo("github", "me", "gickup", "local /backup")
+	logger.Debug().Msg("dropped")
+	logger.Info().Msgf("cloning %s", types.Green("gickup"))
+	ClearRepo()
+	logger.Info().Msg("done")
+	EndRun()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+
+	expected := `"run_id":"` + id + `","hoster":"github","owner":"me","repo":"gickup","destination":"local /backup","message":"cloning gickup"}`
+	if !strings.HasSuffix(lines[0], expected) {
+		t.Errorf("expected %s, got %s", expected, lines[0])
+	}
+
+	if strings.Contains(lines[1], "repo") || !strings.Contains(lines[1], id) {
+		t.Errorf("unexpected fields after the backup: %s", lines[1])
+	}
+}
+
+func TestDuplicateFields(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := zerolog.New(dedupe{zerolog.MultiLevelWriter(&buf)}).Hook(fieldHook{})
+
+	NewRun()
+	SetRepo("github", "me", "gickup", "")
+	logger.Info().Str("repo", "me/dotfiles").Msg("skipping")
+	EndRun()
+
+	line := buf.String()
+	if strings.Count(line, `"repo":`) != 1 || !strings.Contains(line, `"repo":"me/dotfiles"`) {
+		t.Errorf("expected the repo of the line once, got %s", line)
+	}
+
+	if !strings.HasSuffix(line, "}\n") || !strings.Contains(line, `"owner":"me"`) {
+		t.Errorf("unexpected line %s", line)
+	}
+}
@@ -1,15 +1,14 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
-	"io"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
-	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cooperspencer/gickup/onedev"
@@ -17,84 +16,70 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/cooperspencer/gickup/bitbucket"
+	"github.com/cooperspencer/gickup/dashboard"
 	"github.com/cooperspencer/gickup/gitea"
 	"github.com/cooperspencer/gickup/github"
 	"github.com/cooperspencer/gickup/gitlab"
 	"github.com/cooperspencer/gickup/gogs"
 	"github.com/cooperspencer/gickup/local"
 	"github.com/cooperspencer/gickup/logger"
-	"github.com/cooperspencer/gickup/metrics/gotify"
 	"github.com/cooperspencer/gickup/metrics/heartbeat"
-	"github.com/cooperspencer/gickup/metrics/ntfy"
+	"github.com/cooperspencer/gickup/metrics/notify"
 	"github.com/cooperspencer/gickup/metrics/prometheus"
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/report"
+	"github.com/cooperspencer/gickup/status"
+	"github.com/cooperspencer/gickup/tracing"
 	"github.com/cooperspencer/gickup/types"
+	"github.com/cooperspencer/gickup/webhook"
 	"github.com/cooperspencer/gickup/whatever"
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"gopkg.in/yaml.v3"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var cli struct {
-	Configfiles []string `arg name:"conf" help:"Path to the configfile." default:"conf.yml"`
-	Version     bool     `flag name:"version" help:"Show version."`
-	Dry         bool     `flag name:"dryrun" help:"Make a dry-run."`
-	Quiet       bool     `flag name:"quiet" help:"Output only warnings, errors, and fatal messages to stderr log output"`
-	Silent      bool     `flag name:"silent" help:"Suppress all stderr log output"`
+	Run struct {
+		Configfiles []string `arg name:"conf" help:"Path to the configfile." default:"conf.yml"`
+	} `cmd default:"withargs" help:"Backup the repositories (default)."`
+	Validate struct {
+		Configfiles []string `arg name:"conf" help:"Path to the configfile." default:"conf.yml"`
+		Schema      bool     `flag name:"schema" help:"Print the JSON schema of the configuration instead."`
+	} `cmd help:"Check the configfiles for errors."`
+	List struct {
+		Configfiles []string `arg name:"conf" help:"Path to the configfile." default:"conf.yml"`
+		Source      string   `flag name:"source" help:"Only list the repositories of a source, e.g. github, github:1 for the second github source or github:some-user."`
+		Format      string   `flag name:"format" help:"Output format, table, json or csv." enum:"table,json,csv" default:"table"`
+		Skipped     bool     `flag name:"skipped" help:"List the skipped repositories and why they are skipped too."`
+	} `cmd help:"List the repositories of the sources without backing them up."`
+	Version bool   `flag name:"version" help:"Show version."`
+	Dry     bool   `flag name:"dryrun" help:"Make a dry-run."`
+	Plan    string `flag name:"plan" help:"Format of the plan a dry-run prints, table or json." enum:"table,json" default:"table"`
+	Quiet   bool   `flag name:"quiet" help:"Output only warnings, errors, and fatal messages to stderr log output"`
+	Silent  bool   `flag name:"silent" help:"Suppress all stderr log output"`
 }
 
 var version = "unknown"
 
-func readConfigFile(configfile string) []*types.Conf {
-	conf := []*types.Conf{}
-	cfgdata, err := os.ReadFile(configfile)
-	if err != nil {
-		log.Fatal().
-			Str("stage", "readconfig").
-			Str("file", configfile).
-			Msgf("Cannot open config file from %s", types.Red(configfile))
-	}
-
-	//	t := types.Conf{}
-
-	dec := yaml.NewDecoder(bytes.NewReader(cfgdata))
-
-	//	err = yaml.Unmarshal(cfgdata, &t)
-
-	i := 0
-	for {
-		var c *types.Conf
-		err = dec.Decode(&c)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			if len(conf) > 0 {
-				log.Fatal().
-					Str("stage", "readconfig").
-					Str("file", configfile).
-					Msgf("an error occured in the %d place of %s", i, configfile)
-			} else {
-				log.Fatal().
-					Str("stage", "readconfig").
-					Str("file", configfile).
-					Msg(err.Error())
-			}
-		}
-
-		if !reflect.ValueOf(c).IsZero() {
-			if len(conf) > 0 {
-				if len(c.Metrics.PushConfigs.Gotify) == 0 && len(c.Metrics.PushConfigs.Ntfy) == 0 {
-					c.Metrics.PushConfigs = conf[0].Metrics.PushConfigs
-				}
-			}
-			conf = append(conf, c)
-			i++
-		}
-	}
-
-	return conf
+// sources are the hosters repositories are grabbed from, in the order they are backed up.
+var sources = []struct {
+	name string
+	get  func(*types.Conf) ([]types.Repo, bool)
+}{
+	{"github", github.Get},
+	{"gitea", gitea.Get},
+	{"gogs", gogs.Get},
+	{"gitlab", gitlab.Get},
+	{"bitbucket", bitbucket.Get},
+	{"whatever", whatever.Get},
+	{"onedev", onedev.Get},
+	{"sourcehut", sourcehut.Get},
 }
 
+// backupMutex makes sure only one backup runs at a time, local backups change the working directory.
+var backupMutex sync.Mutex
+
 func getUserHome() (string, error) {
 	usr, err := user.Current()
 	if err != nil {
@@ -136,10 +121,31 @@ func substituteHomeForTildeInPath(path string) string {
 	return path
 }
 
-func backup(repos []types.Repo, conf *types.Conf) {
-	checkedpath := false
+// workdir is the working directory gickup was started in, local backups change it.
+var workdir, _ = os.Getwd()
+
+// localPath returns the absolute path of a local destination or a report, relative paths are relative to workdir.
+func localPath(path string) string {
+	if path == "" {
+		return path
+	}
+
+	path = substituteHomeForTildeInPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workdir, path)
+	}
+
+	return filepath.Clean(path)
+}
+
+func backup(repos []types.Repo, conf *types.Conf, num int) {
+	defer logger.ClearRepo()
 
 	for _, r := range repos {
+		waitForWindow(conf)
+
+		logger.SetRepo(r.Hoster, r.Owner, r.Name, "")
+
 		log.Info().
 			Str("stage", "backup").
 			Msgf("starting backup for %s", r.URL)
@@ -148,71 +154,103 @@ func backup(repos []types.Repo, conf *types.Conf) {
 			log.Warn().Str("stage", "backup").Msg("No destinations configured!")
 		}
 		for i, d := range conf.Destination.Local {
-			if !checkedpath {
-				d.Path = substituteHomeForTildeInPath(d.Path)
-
-				path, err := filepath.Abs(d.Path)
-				if err != nil {
-					log.Fatal().
-						Str("stage", "locally").
-						Str("path", d.Path).
-						Msg(err.Error())
-				}
+			repotime := time.Now()
+			mark := status.Logs.Mark()
+			before := status.Size(local.RepoPath(r, conf.Destination.Local[i]), local.ArchiveSuffix(d))
+			success := 0
+			logger.SetRepo(r.Hoster, r.Owner, r.Name, "local "+d.Path)
+			span := backupSpan(r, "local", d.Path)
+			ok := local.Locally(r, d, cli.Dry)
+			span.Finish(ok)
+			if ok {
+				prometheus.RepoTime.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(time.Now().Sub(repotime).Seconds())
+				success = 1
+			}
 
-				conf.Destination.Local[i].Path = path
-				checkedpath = true
+			size := status.Size(local.RepoPath(r, conf.Destination.Local[i]), local.ArchiveSuffix(d))
+			growth := int64(0)
+			if size > before {
+				growth = size - before
 			}
 
-			repotime := time.Now()
-			status := 0
-			if local.Locally(r, d, cli.Dry) {
-				prometheus.RepoTime.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(time.Now().Sub(repotime).Seconds())
-				status = 1
+			record(num, conf.Job, r, "local", d.Path, success == 1, time.Since(repotime), size, growth, status.Logs.Since(mark))
+
+			prometheus.RepoSizeGrowth.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(float64(growth))
+			prometheus.RepoSize.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(float64(size))
+			if objects, ok := local.ObjectCount(local.RepoPath(r, conf.Destination.Local[i])); ok {
+				prometheus.RepoObjects.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(float64(objects))
 			}
 
-			prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(float64(status))
+			prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "local", d.Path).Set(float64(success))
 			prometheus.DestinationBackupsComplete.WithLabelValues("local").Inc()
 		}
 
 		for _, d := range conf.Destination.Gitea {
 			if !strings.HasSuffix(r.Name, ".wiki") {
 				repotime := time.Now()
-				status := 0
-				if gitea.Backup(r, d, cli.Dry) {
+				mark := status.Logs.Mark()
+				success := 0
+				logger.SetRepo(r.Hoster, r.Owner, r.Name, "gitea "+d.URL)
+				span := backupSpan(r, "gitea", d.URL)
+				ok := gitea.Backup(r, d, cli.Dry)
+				span.Finish(ok)
+				if ok {
 					prometheus.RepoTime.WithLabelValues(r.Hoster, r.Name, r.Owner, "gitea", d.URL).Set(time.Now().Sub(repotime).Seconds())
-					status = 1
+					success = 1
 				}
 
-				prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "gitea", d.URL).Set(float64(status))
+				record(num, conf.Job, r, "gitea", d.URL, success == 1, time.Since(repotime), 0, 0, status.Logs.Since(mark))
+
+				prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "gitea", d.URL).Set(float64(success))
 				prometheus.DestinationBackupsComplete.WithLabelValues("gitea").Inc()
+			} else {
+				skip(num, conf.Job, r.Step("gitea "+d.URL, plan.Skip, "wikis are only backed up locally"))
 			}
 		}
 
 		for _, d := range conf.Destination.Gogs {
 			if !strings.HasSuffix(r.Name, ".wiki") {
 				repotime := time.Now()
-				status := 0
-				if gogs.Backup(r, d, cli.Dry) {
+				mark := status.Logs.Mark()
+				success := 0
+				logger.SetRepo(r.Hoster, r.Owner, r.Name, "gogs "+d.URL)
+				span := backupSpan(r, "gogs", d.URL)
+				ok := gogs.Backup(r, d, cli.Dry)
+				span.Finish(ok)
+				if ok {
 					prometheus.RepoTime.WithLabelValues(r.Hoster, r.Name, r.Owner, "gogs", d.URL).Set(time.Now().Sub(repotime).Seconds())
-					status = 1
+					success = 1
 				}
 
-				prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "gogs", d.URL).Set(float64(status))
+				record(num, conf.Job, r, "gogs", d.URL, success == 1, time.Since(repotime), 0, 0, status.Logs.Since(mark))
+
+				prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "gogs", d.URL).Set(float64(success))
 				prometheus.DestinationBackupsComplete.WithLabelValues("gogs").Inc()
+			} else {
+				skip(num, conf.Job, r.Step("gogs "+d.URL, plan.Skip, "wikis are only backed up locally"))
 			}
 		}
 
 		for _, d := range conf.Destination.Gitlab {
 			if !strings.HasSuffix(r.Name, ".wiki") {
 				repotime := time.Now()
-				status := 0
-				if gitlab.Backup(r, d, cli.Dry) {
+				mark := status.Logs.Mark()
+				success := 0
+				logger.SetRepo(r.Hoster, r.Owner, r.Name, "gitlab "+d.URL)
+				span := backupSpan(r, "gitlab", d.URL)
+				ok := gitlab.Backup(r, d, cli.Dry)
+				span.Finish(ok)
+				if ok {
 					prometheus.RepoTime.WithLabelValues(r.Hoster, r.Name, r.Owner, "gitlab", d.URL).Set(time.Now().Sub(repotime).Seconds())
-					status = 1
+					success = 1
 				}
 
-				prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "gitlab", d.URL).Set(float64(status))
+				record(num, conf.Job, r, "gitlab", d.URL, success == 1, time.Since(repotime), 0, 0, status.Logs.Since(mark))
+
+				prometheus.RepoSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, "gitlab", d.URL).Set(float64(success))
 				prometheus.DestinationBackupsComplete.WithLabelValues("gitlab").Inc()
+			} else {
+				skip(num, conf.Job, r.Step("gitlab "+d.URL, plan.Skip, "wikis are only backed up locally"))
 			}
 		}
 
@@ -220,66 +258,177 @@ func backup(repos []types.Repo, conf *types.Conf) {
 	}
 }
 
-func runBackup(conf *types.Conf, num int) {
-	log.Info().Msg("Backup run starting")
+// backupSpan starts the span of the backup of r to a destination.
+func backupSpan(r types.Repo, destination, path string) *tracing.Span {
+	return tracing.Start("backup "+destination,
+		attribute.String("hoster", r.Hoster),
+		attribute.String("owner", r.Owner),
+		attribute.String("repo", r.Name),
+		attribute.String("destination", destination),
+		attribute.String("path", path))
+}
 
-	numstring := strconv.Itoa(num)
+// record stores the outcome of the backup of r to a destination for the dashboard and the report.
+func record(num, job int, r types.Repo, destination, path string, success bool, duration time.Duration, size, transferred int64, logs []string) {
+	status.Record(num, r, destination, path, success, duration, size, logs)
+
+	result := report.Result{
+		Hoster:      r.Hoster,
+		Owner:       r.Owner,
+		Name:        r.Name,
+		Destination: destination + " " + path,
+		Status:      report.Success,
+		Duration:    duration.Seconds(),
+		Bytes:       transferred,
+		Size:        size,
+	}
 
-	startTime := time.Now()
+	if success && !cli.Dry {
+		prometheus.RepoLastSuccess.WithLabelValues(r.Hoster, r.Name, r.Owner, destination, path).SetToCurrentTime()
+	} else if !success {
+		result.Status = report.Failure
+		result.Error = report.ErrorOf(logs)
+		result.Log = logs
 
-	prometheus.JobsStarted.Inc()
+		prometheus.Errors.WithLabelValues(r.Hoster, destination, prometheus.ErrorClass(result.Error)).Inc()
+	}
+
+	report.Add(num, job, result)
+}
 
-	// Github
-	repos, ran := github.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("github", numstring).Set(float64(len(repos)))
+// skip adds a skipped repository to the plan of a dry-run and the report.
+func skip(num, job int, step plan.Step) {
+	plan.Add(step)
+	report.Add(num, job, skippedResult(step))
+}
+
+func skippedResult(step plan.Step) report.Result {
+	owner, name := "", step.Repo
+	if i := strings.LastIndex(step.Repo, "/"); i >= 0 {
+		owner, name = step.Repo[:i], step.Repo[i+1:]
 	}
-	backup(repos, conf)
 
-	// Gitea
-	repos, ran = gitea.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("gitea", numstring).Set(float64(len(repos)))
+	return report.Result{
+		Hoster:      step.Source,
+		Owner:       owner,
+		Name:        name,
+		Destination: step.Destination,
+		Status:      report.Skipped,
+		Reason:      step.Reason,
 	}
-	backup(repos, conf)
+}
 
-	// Gogs
-	repos, ran = gogs.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("gogs", numstring).Set(float64(len(repos)))
+// writeReport writes the report of the backup runs to the files of the report configuration.
+func writeReport(conf types.ReportConfig) {
+	r := report.Get()
+
+	for _, file := range []struct {
+		path  string
+		write func(string) error
+	}{
+		{conf.JSON, r.WriteJSON},
+		{conf.JUnit, r.WriteJUnit},
+	} {
+		if file.path == "" {
+			continue
+		}
+
+		if err := file.write(file.path); err != nil {
+			log.Error().
+				Str("stage", "report").
+				Str("file", file.path).
+				Msg(err.Error())
+		}
 	}
-	backup(repos, conf)
+}
 
-	// Gitlab
-	repos, ran = gitlab.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("gitlab", numstring).Set(float64(len(repos)))
+// waitForWindow waits for the window of conf in the middle of a run. The backups of other configurations
+// can run in the meantime, so the state of the run is put aside until the backup lock is taken again.
+func waitForWindow(conf *types.Conf) {
+	if conf.InWindow() {
+		return
 	}
-	backup(repos, conf)
 
-	repos, ran = bitbucket.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("bitbucket", numstring).Set(float64(len(repos)))
+	fields := logger.Suspend()
+	span := tracing.Suspend()
+	steps := plan.Take()
+
+	backupMutex.Unlock()
+	sleepUntilWindow(conf)
+	backupMutex.Lock()
+
+	for _, step := range steps {
+		plan.Add(step)
 	}
-	backup(repos, conf)
 
-	repos, ran = whatever.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("whatever", numstring).Set(float64(len(repos)))
+	tracing.Resume(span)
+	logger.Resume(fields)
+}
+
+// sleepUntilWindow blocks until the window of conf is open.
+func sleepUntilWindow(conf *types.Conf) {
+	next := conf.NextWindow()
+	if !next.After(time.Now()) {
+		return
 	}
-	backup(repos, conf)
 
-	repos, ran = onedev.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("onedev", numstring).Set(float64(len(repos)))
+	logger.Background().Info().
+		Str("window", fmt.Sprintf("%s-%s", conf.Window.Start, conf.Window.End)).
+		Str("resume", next.String()).
+		Msg("outside of the execution window, pausing")
+
+	time.Sleep(time.Until(next))
+}
+
+func runBackup(conf *types.Conf, num int) {
+	// waiting for the window doesn't block the runs of other configurations
+	sleepUntilWindow(conf)
+
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	runID := logger.NewRun()
+	defer logger.EndRun()
+
+	log.Info().Msg("Backup run starting")
+
+	numstring := strconv.Itoa(num)
+
+	startTime := time.Now()
+
+	prometheus.JobsStarted.Inc()
+
+	report.Start(num, conf.Job)
+	if len(conf.Metrics.Heartbeat.Checks) > 0 {
+		heartbeat.Start(conf.Metrics.Heartbeat, num, conf.Job, runID)
 	}
-	backup(repos, conf)
 
-	repos, ran = sourcehut.Get(conf)
-	if ran {
-		prometheus.CountReposDiscovered.WithLabelValues("sourcehut", numstring).Set(float64(len(repos)))
+	if conf.Report.IsSet() || conf.Metrics.PushConfigs.IsSet() {
+		// the skipped repositories of the sources are reported and notified too
+		plan.Enable()
 	}
-	backup(repos, conf)
+
+	run := tracing.Start("backup run", attribute.Int("config", num))
+
+	for _, source := range sources {
+		span := tracing.Start("get "+source.name, attribute.String("source", source.name))
+		repos, ran := source.get(conf)
+		span.Set(attribute.Int("repos", len(repos)))
+		span.End(nil)
+
+		types.SortRepos(repos, conf.Order)
+		if ran {
+			prometheus.CountReposDiscovered.WithLabelValues(source.name, numstring).Set(float64(len(repos)))
+			discovered.set(num, conf.Job, source.name, repos)
+		}
+		backup(repos, conf, num)
+	}
+
+	report.Finish(num, conf.Job)
+
+	latest, _ := report.Latest(num, conf.Job)
+	run.Set(attribute.Int("failed", latest.Failed))
+	run.Finish(latest.Failed == 0)
 
 	endTime := time.Now()
 	duration := endTime.Sub(startTime)
@@ -287,39 +436,52 @@ func runBackup(conf *types.Conf, num int) {
 	prometheus.JobsComplete.Inc()
 	prometheus.JobDuration.Observe(duration.Seconds())
 
-	if len(conf.Metrics.Heartbeat.URLs) > 0 {
-		heartbeat.Send(conf.Metrics.Heartbeat)
+	steps := plan.Take()
+	for _, step := range steps {
+		// skipped wikis were reported by backup already
+		if step.Action == plan.Skip && step.Destination == "" {
+			report.Add(num, conf.Job, skippedResult(step))
+		}
 	}
 
-	if len(conf.Metrics.PushConfigs.Ntfy) > 0 {
-		for _, pusher := range conf.Metrics.PushConfigs.Ntfy {
-			pusher.ResolveToken()
-			err := ntfy.Notify(fmt.Sprintf("backup took %v", duration), *pusher)
-			if err != nil {
-				log.Warn().Str("push", "ntfy").Err(err).Msg("couldn't send message")
-			}
-		}
+	if conf.Metrics.Prometheus.Pushgateway.URL != "" {
+		prometheus.Push(conf.Metrics.Prometheus.Pushgateway)
 	}
 
-	if len(conf.Metrics.PushConfigs.Gotify) > 0 {
-		for _, pusher := range conf.Metrics.PushConfigs.Gotify {
-			pusher.ResolveToken()
-			err := gotify.Notify(fmt.Sprintf("backup took %v", duration), *pusher)
-			if err != nil {
-				log.Warn().Str("push", "gotify").Err(err).Msg("couldn't send message")
-			}
-		}
+	if conf.Metrics.Heartbeat.IsSet() {
+		heartbeat.Send(conf.Metrics.Heartbeat, num, conf.Job, duration)
 	}
 
+	notify.Send(conf.Metrics.PushConfigs, notify.Summarize(num, conf.Job, duration))
+
 	log.Info().
 		Str("duration", duration.String()).
 		Msg("Backup run complete")
 
+	if conf.Report.IsSet() {
+		writeReport(conf.Report)
+	}
+
+	if cli.Dry {
+		writePlan(steps)
+	}
+
+	tracing.Flush()
+
 	if conf.HasValidCronSpec() {
 		logNextRun(conf)
 	}
 }
 
+// writePlan prints what the dry-run would have done.
+func writePlan(steps []plan.Step) {
+	if err := plan.Write(os.Stdout, steps, cli.Plan); err != nil {
+		log.Error().
+			Str("stage", "plan").
+			Msg(err.Error())
+	}
+}
+
 func playsForever() {
 	wait := make(chan struct{})
 
@@ -328,19 +490,24 @@ func playsForever() {
 	}
 }
 
-func main() {
-	timeformat := "2006-01-02T15:04:05Z07:00"
-
+// timeFormat returns the format of the timestamps in the logs, it can be set with GICKUP_TIME_FORMAT.
+func timeFormat() string {
 	if len(os.Getenv("GICKUP_TIME_FORMAT")) > 0 {
-		timeformat = os.Getenv("GICKUP_TIME_FORMAT")
+		return os.Getenv("GICKUP_TIME_FORMAT")
 	}
 
+	return "2006-01-02T15:04:05Z07:00"
+}
+
+func main() {
+	timeformat := timeFormat()
+
 	log.Logger = log.Output(zerolog.ConsoleWriter{
 		Out:        os.Stderr,
 		TimeFormat: timeformat,
 	})
 
-	kong.Parse(&cli, kong.Name("gickup"),
+	ctx := kong.Parse(&cli, kong.Name("gickup"),
 		kong.Description("a tool to backup all your favorite repos"))
 
 	if cli.Version {
@@ -349,6 +516,10 @@ func main() {
 		return
 	}
 
+	if strings.HasPrefix(ctx.Command(), "validate") {
+		os.Exit(validate(cli.Validate.Configfiles, cli.Validate.Schema))
+	}
+
 	if cli.Quiet {
 		zerolog.SetGlobalLevel(zerolog.WarnLevel)
 	}
@@ -357,14 +528,19 @@ func main() {
 		zerolog.SetGlobalLevel(zerolog.Disabled)
 	}
 
+	if strings.HasPrefix(ctx.Command(), "list") {
+		os.Exit(list(cli.List.Configfiles, cli.List.Source, cli.List.Format, cli.List.Skipped))
+	}
+
 	if cli.Dry {
 		log.Info().
 			Str("dry", "true").
 			Msgf("this is a %s", types.Blue("dry run"))
+		plan.Enable()
 	}
 
 	confs := []*types.Conf{}
-	for _, f := range cli.Configfiles {
+	for _, f := range cli.Run.Configfiles {
 		log.Info().Str("file", f).
 			Msgf("Reading %s", types.Green(f))
 
@@ -376,7 +552,24 @@ func main() {
 
 	log.Logger = logger.CreateLogger(confs[0].Log)
 
-	validcron := confs[0].HasValidCronSpec()
+	if err := tracing.Init(confs[0].Metrics.Tracing); err != nil {
+		log.Error().
+			Str("stage", "tracing").
+			Msg(err.Error())
+	}
+
+	if err := inheritCron(confs); err != nil {
+		log.Fatal().
+			Str("stage", "cron").
+			Msg(err.Error())
+	}
+
+	validcron := false
+	for _, job := range confs[0].Split() {
+		if job.HasValidCronSpec() {
+			validcron = true
+		}
+	}
 
 	var c *cron.Cron
 
@@ -385,9 +578,90 @@ func main() {
 		c.Start()
 	}
 
+	if err := schedule(c, confs, true); err != nil {
+		log.Fatal().
+			Str("stage", "cron").
+			Msg(err.Error())
+	}
+
+	if validcron {
+		go watchConfig(c, cli.Run.Configfiles)
+	}
+
+	if validcron || confs[0].HasWebhookConf() {
+		if confs[0].HasWebhookConf() {
+			if confs[0].Webhook.GetSecret() == "" {
+				log.Fatal().
+					Str("stage", "webhook").
+					Msg("webhook.secret is required, without it anyone could trigger backups")
+			}
+
+			if confs[0].Webhook.ListenAddr == confs[0].Metrics.Prometheus.ListenAddr {
+				http.Handle(confs[0].Webhook.GetEndpoint(), webhook.Handler(confs[0].Webhook, triggerBackup(active.get)))
+			} else {
+				go webhook.Serve(confs[0].Webhook, triggerBackup(active.get))
+			}
+		}
+
+		if confs[0].HasDashboardConf() {
+			if confs[0].Metrics.Dashboard.ListenAddr == confs[0].Metrics.Prometheus.ListenAddr {
+				http.Handle(confs[0].Metrics.Dashboard.GetEndpoint(), dashboard.Handler(active.get))
+			} else {
+				go dashboard.Serve(confs[0].Metrics.Dashboard, active.get)
+			}
+		}
+
+		if confs[0].HasAllPrometheusConf() {
+			prometheus.Serve(confs[0].Metrics.Prometheus)
+		} else {
+			playsForever()
+		}
+	}
+
+	if report.Failed() {
+		log.Error().
+			Str("stage", "backup").
+			Msg("the backup of at least one repository failed")
+		os.Exit(1)
+	}
+}
+
+// inheritCron lets configurations without a cron use the one of the first configuration, with its
+// timezone and, unless they have their own, its jitter and window. An invalid cron or timezone is an
+// error, the configuration would run on another schedule or only once otherwise.
+func inheritCron(confs []*types.Conf) error {
+	for num, conf := range confs {
+		if !conf.MissingCronSpec() {
+			if _, err := types.ParseCronSpec(conf.CronSpec()); err != nil {
+				return fmt.Errorf("configuration %d: %s", num, err.Error())
+			}
+
+			continue
+		}
+
+		conf.Cron = confs[0].Cron
+		conf.Timezone = confs[0].Timezone
+
+		if conf.Jitter == "" {
+			conf.Jitter = confs[0].Jitter
+		}
+
+		if !conf.Window.IsSet() {
+			conf.Window = confs[0].Window
+		}
+	}
+
+	return nil
+}
+
+// schedule adds a cron job for every job of confs and makes them the active configurations,
+// the jobs which were active before are removed. Running jobs aren't interrupted.
+// Jobs without a valid cron spec are run right away if runonce is set.
+func schedule(c *cron.Cron, confs []*types.Conf, runonce bool) error {
+	entries := []cron.EntryID{}
 	sourcecount := 0
 	destinationcount := 0
-	// one pair per source-destination
+
 	for num, conf := range confs {
 		pairs := conf.Source.Count() * conf.Destination.Count()
 		sourcecount += conf.Source.Count()
@@ -398,40 +672,51 @@ func main() {
 			Int("pairs", pairs).
 			Msg("Configuration loaded")
 
-		if !conf.HasValidCronSpec() {
-			conf.Cron = confs[0].Cron
-		}
+		for _, job := range conf.Split() {
+			if job.HasValidCronSpec() && c != nil {
+				job := job // https://stackoverflow.com/questions/57095167/how-do-i-create-multiple-cron-function-by-looping-through-a-list
+				num := num
 
-		if conf.HasValidCronSpec() && validcron {
-			conf := conf // https://stackoverflow.com/questions/57095167/how-do-i-create-multiple-cron-function-by-looping-through-a-list
-			num := num
+				logNextRun(job)
 
-			logNextRun(conf)
+				id, err := c.AddFunc(job.CronSpec(), func() {
+					if jitter := job.GetJitter(); jitter > 0 {
+						logger.Background().Info().
+							Str("jitter", jitter.String()).
+							Msg("delaying backup run")
+						time.Sleep(jitter)
+					}
 
-			_, err := c.AddFunc(conf.Cron, func() {
-				runBackup(conf, num)
-			})
-			if err != nil {
-				log.Fatal().
-					Int("sources", conf.Source.Count()).
-					Int("destinations", conf.Destination.Count()).
-					Int("pairs", pairs).
-					Msg(err.Error())
+					runBackup(job, num)
+				})
+				if err != nil {
+					for _, id := range entries {
+						c.Remove(id)
+					}
+
+					return err
+				}
+
+				entries = append(entries, id)
+			} else if runonce {
+				runBackup(job, num)
+			} else {
+				log.Warn().
+					Str("stage", "reload").
+					Int("config", num).
+					Msg("job has no valid cron spec and is skipped")
 			}
-		} else {
-			runBackup(conf, num)
 		}
 	}
 
-	if validcron {
-		if confs[0].HasAllPrometheusConf() {
-			prometheus.CountSourcesConfigured.Add(float64(sourcecount))
-			prometheus.CountDestinationsConfigured.Add(float64(destinationcount))
-			prometheus.Serve(confs[0].Metrics.Prometheus)
-		} else {
-			playsForever()
-		}
+	for _, id := range active.swap(confs, entries) {
+		c.Remove(id)
 	}
+
+	prometheus.CountSourcesConfigured.Set(float64(sourcecount))
+	prometheus.CountDestinationsConfigured.Set(float64(destinationcount))
+
+	return nil
 }
 
 func logNextRun(conf *types.Conf) {
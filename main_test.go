@@ -1,8 +1,14 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/cooperspencer/gickup/types"
 )
 
 func TestTildeReplacement_NoAction(t *testing.T) {
@@ -34,3 +40,283 @@ func TestTildeReplacement_TildeDir(t *testing.T) {
 		t.Error("Altered path does not end with directory to be retained")
 	}
 }
+
+func TestExampleConfigHasKnownFields(t *testing.T) {
+	t.Parallel()
+
+	if _, err := loadConfigFile("conf.example.yml", true); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchemaIsUpToDate(t *testing.T) {
+	t.Parallel()
+
+	schema, err := types.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile("conf.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.TrimSpace(string(data)) != string(schema) {
+		t.Error("conf.schema.json is outdated, regenerate it with gickup validate --schema > conf.schema.json")
+	}
+}
+
+func TestIncludesAndTemplates(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"conf.yml": `include:
+  - sources/*.yml
+defaults:
+  corp-gitea:
+    url: https://gitea.example.com
+    token: corp-token
+    filter:
+      excludearchived: true
+      stars: 1
+source:
+  gitea:
+    - extends: corp-gitea
+      user: alice
+      filter:
+        stars: 5
+destination:
+  local:
+    - path: /backup
+`,
+		"sources/bob.yml": `source:
+  gitea:
+    - extends: corp-gitea
+      user: bob
+`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	confs, err := loadConfigFile(filepath.Join(dir, "conf.yml"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gitea := confs[0].Source.Gitea
+	if len(gitea) != 2 {
+		t.Fatalf("expected 2 gitea sources, got %d", len(gitea))
+	}
+
+	for _, repo := range gitea {
+		if repo.URL != "https://gitea.example.com" || repo.Token != "corp-token" || !repo.Filter.ExcludeArchived {
+			t.Errorf("template wasn't applied to %s: %+v", repo.User, repo)
+		}
+	}
+
+	if gitea[0].User != "alice" || gitea[0].Filter.Stars != 5 {
+		t.Errorf("template overwrote the values of alice: %+v", gitea[0])
+	}
+
+	if gitea[1].User != "bob" || gitea[1].Filter.Stars != 1 {
+		t.Errorf("included source is wrong: %+v", gitea[1])
+	}
+}
+
+func TestDiffConfs(t *testing.T) {
+	old := []*types.Conf{{
+		Cron: "0 22 * * *",
+		Source: types.Source{
+			Github: []types.GenRepo{{User: "alice"}, {User: "bob"}},
+		},
+		Destination: types.Destination{
+			Local: []types.Local{{Path: "/backups"}},
+		},
+	}}
+
+	new := []*types.Conf{{
+		Cron: "0 23 * * *",
+		Source: types.Source{
+			Github: []types.GenRepo{{User: "alice", Exclude: []string{"dotfiles"}}, {User: "carol"}},
+		},
+		Destination: types.Destination{
+			Local: []types.Local{{Path: "/backups"}},
+		},
+	}, {
+		Source: types.Source{
+			Gitea: []types.GenRepo{{User: "dave", URL: "https://gitea.example.com"}},
+		},
+	}}
+
+	expected := []string{
+		"config 0: changed cron",
+		"config 0: changed source github alice",
+		"config 0: removed source github bob",
+		"config 0: added source github carol",
+		"config 1: added",
+		"config 1: added source gitea dave@https://gitea.example.com",
+	}
+
+	changes := diffConfs(old, new)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %q, got %q", expected, changes)
+	}
+
+	if changes := diffConfs(old, old); len(changes) != 0 {
+		t.Errorf("expected no changes, got %q", changes)
+	}
+}
+
+func TestInheritCron(t *testing.T) {
+	window := types.Window{Start: "22:00", End: "06:00"}
+	confs := []*types.Conf{
+		{Cron: "0 22 * * *", Timezone: "Europe/Berlin", Jitter: "10m", Window: window},
+		{},
+		{Jitter: "1m", Window: types.Window{Start: "01:00", End: "02:00"}},
+	}
+
+	if err := inheritCron(confs); err != nil {
+		t.Fatal(err)
+	}
+
+	if confs[1].Cron != "0 22 * * *" || confs[1].Timezone != "Europe/Berlin" || confs[1].Jitter != "10m" || confs[1].Window != window {
+		t.Errorf("the schedule wasn't inherited: %+v", confs[1])
+	}
+
+	if confs[2].Jitter != "1m" || confs[2].Window.Start != "01:00" {
+		t.Errorf("the own jitter and window were replaced: %+v", confs[2])
+	}
+
+	for _, invalid := range []*types.Conf{{Cron: "0 22 * * *", Timezone: "Nowhere/Atlantis"}, {Cron: "redshirt"}} {
+		if err := inheritCron([]*types.Conf{confs[0], invalid}); err == nil {
+			t.Errorf("%+v fell back to the schedule of the first configuration", invalid)
+		}
+	}
+}
+
+func TestHostedOn(t *testing.T) {
+	conf := &types.Conf{Source: types.Source{
+		Github: []types.GenRepo{{User: "me"}},
+		Gitea:  []types.GenRepo{{User: "me"}, {User: "me", URL: "https://git.example.com/"}},
+	}}
+
+	for _, c := range []struct {
+		source, hoster string
+		expected       bool
+	}{
+		{"github", "github.com", true},
+		{"gitea", "gitea.com", true},
+		{"gitea", "git.example.com", true},
+		{"gitea", "github.com", false},
+		{"gitlab", "gitlab.com", false},
+	} {
+		if hostedOn(conf, c.source, c.hoster) != c.expected {
+			t.Errorf("expected %s on %s to be %v", c.source, c.hoster, c.expected)
+		}
+	}
+}
+
+func TestDiscoveredKeepsJobsApart(t *testing.T) {
+	cache := &repoCache{repos: map[job]map[string][]types.Repo{}, refreshed: map[job]map[string]time.Time{}}
+	gickup := types.Repo{Hoster: "github.com", Owner: "me", Name: "gickup"}
+
+	cache.set(0, 0, "github", []types.Repo{gickup})
+	cache.set(0, 1, "github", []types.Repo{gickup, {Hoster: "github.com", Owner: "me", Name: "dotfiles"}})
+	cache.set(1, 0, "github", []types.Repo{{Hoster: "github.com", Owner: "other", Name: "repo"}})
+
+	if repos := cache.get(0); len(repos) != 2 {
+		t.Errorf("expected the repositories of both jobs once, got %v", repos)
+	}
+
+	if cache.stale(0, 1, "github") || !cache.stale(0, 2, "github") {
+		t.Error("the jobs share when their sources were listed")
+	}
+}
+
+func TestDecodeKeepsLinesAndStrings(t *testing.T) {
+	t.Setenv("GICKUP_TEST_NULL", "null")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf.yml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("source:\n  github:\n    - user: me\n      token: ${GICKUP_TEST_NULL}\n      stars: 5\n")
+	if _, err := loadConfigFile(path, true); err == nil || !strings.Contains(err.Error(), "line 5: field stars not found") {
+		t.Errorf("expected an error in line 5, got %v", err)
+	}
+
+	write("source:\n  github:\n    - user: me\n      token: ${GICKUP_TEST_NULL}\n")
+	confs, err := loadConfigFile(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token := confs[0].Source.Github[0].Token; token != "null" {
+		t.Errorf("expected the token null, got %q", token)
+	}
+}
+
+func TestIncludeFileAndLocalExtends(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"conf.yml":   "include: local.yml\nsource:\n  github:\n    - user: me\n",
+		"local.yml":  "destination:\n  local:\n    - path: /backup\n",
+		"extend.yml": "defaults:\n  corp:\n    token: corp-token\ndestination:\n  local:\n    - extends: corp\n      path: /backup\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	confs, err := loadConfigFile(filepath.Join(dir, "conf.yml"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(confs[0].Destination.Local) != 1 {
+		t.Errorf("the included file wasn't merged: %+v", confs[0].Destination)
+	}
+
+	if _, err := loadConfigFile(filepath.Join(dir, "extend.yml"), true); err == nil || !strings.Contains(err.Error(), "line 6: local destinations can't extend") {
+		t.Errorf("expected an error for extends of a local destination, got %v", err)
+	}
+}
+
+func TestLocalPathsAreResolvedOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.yml")
+	if err := os.WriteFile(path, []byte("destination:\n  local:\n    - path: backup\nreport:\n  json: report.json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := loadConfigFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := filepath.Join(workdir, "backup"); old[0].Destination.Local[0].Path != expected {
+		t.Errorf("expected %s, got %s", expected, old[0].Destination.Local[0].Path)
+	}
+
+	if expected := filepath.Join(workdir, "report.json"); old[0].Report.JSON != expected || old[0].Report.JUnit != "" {
+		t.Errorf("expected the report at %s, got %+v", expected, old[0].Report)
+	}
+
+	new, _ := loadConfigFile(path, false)
+	if changes := diffConfs(old, new); len(changes) != 0 {
+		t.Errorf("expected no changes, got %q", changes)
+	}
+}
@@ -0,0 +1,27 @@
+package discord
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cooperspencer/gickup/metrics/push"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// discord rejects messages longer than 2000 characters
+const maxLength = 2000
+
+// Notify posts the notification to a discord webhook.
+func Notify(notification types.Notification, config types.PushConfig) error {
+	content := []rune(fmt.Sprintf("**%s**\n%s", notification.Title, notification.Message))
+	if len(content) > maxLength {
+		content = append(content[:maxLength-1], '…')
+	}
+
+	payload := map[string]string{
+		"username": "gickup",
+		"content":  string(content),
+	}
+
+	return push.JSON(http.MethodPost, config.Url, payload, nil)
+}
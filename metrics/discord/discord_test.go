@@ -0,0 +1,46 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/cooperspencer/gickup/types"
+)
+
+func TestNotify(t *testing.T) {
+	payload := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s, %s", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notification := types.Notification{Title: "Backup failed", Message: "me/broken to gitea: timeout"}
+	if err := Notify(notification, types.PushConfig{Url: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload["username"] != "gickup" || payload["content"] != "**Backup failed**\nme/broken to gitea: timeout" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+
+	notification.Message = strings.Repeat("ä", 3*maxLength)
+	if err := Notify(notification, types.PushConfig{Url: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	if content := payload["content"]; utf8.RuneCountInString(content) != maxLength || !strings.HasSuffix(content, "…") {
+		t.Errorf("expected the content cut to %d characters, got %d", maxLength, utf8.RuneCountInString(content))
+	}
+}
@@ -7,10 +7,11 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/cooperspencer/gickup/metrics/push"
 	"github.com/cooperspencer/gickup/types"
 )
 
-func Notify(msg string, config types.PushConfig) error {
+func Notify(notification types.Notification, config types.PushConfig) error {
 	if !strings.HasSuffix(config.Url, "/") {
 		config.Url += "/"
 	}
@@ -18,8 +19,8 @@ func Notify(msg string, config types.PushConfig) error {
 	url := fmt.Sprintf("%smessage?token=%s", config.Url, config.Token)
 
 	payload := map[string]string{}
-	payload["message"] = msg
-	payload["title"] = "Backup done"
+	payload["message"] = notification.Message
+	payload["title"] = notification.Title
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -30,7 +31,10 @@ func Notify(msg string, config types.PushConfig) error {
 
 	req.Header.Add("Content-Type", "application/json")
 
-	res, _ := http.DefaultClient.Do(req)
+	res, err := push.Client.Do(req)
+	if err != nil {
+		return err
+	}
 
 	res.Body.Close()
 
@@ -1,18 +1,153 @@
 package heartbeat
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/cooperspencer/gickup/report"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/rs/zerolog/log"
 )
 
-func Send(conf types.HeartbeatConfig) {
+// healthchecks.io accepts up to 100 KB, the excerpt stays well below
+const maxExcerpt = 10 * 1024
+
+// key identifies a job of a configuration.
+type key struct {
+	num, job int
+}
+
+var (
+	mu   sync.Mutex
+	rids = map[key]string{}
+)
+
+// Start pings the start of the run of the job of the configuration num to the checks that want it.
+// The run id lets the service match the start and the end of the run.
+func Start(conf types.HeartbeatConfig, num, job int, rid string) {
+	mu.Lock()
+	rids[key{num, job}] = rid
+	mu.Unlock()
+
+	client := &http.Client{Timeout: conf.GetTimeout()}
+	for _, check := range conf.Checks {
+		if check.Start {
+			ping(client, http.MethodGet, checkURL(check.URL, "/start", rid), "")
+		}
+	}
+}
+
+// Send pings the urls and checks at the end of the run of the job of the configuration num. Checks are
+// pinged with url/fail if a repository failed and they want it.
+func Send(conf types.HeartbeatConfig, num, job int, duration time.Duration) {
+	mu.Lock()
+	rid := rids[key{num, job}]
+	delete(rids, key{num, job})
+	mu.Unlock()
+
+	client := &http.Client{Timeout: conf.GetTimeout()}
 	for _, u := range conf.URLs {
-		log.Info().Str("url", u).Msg("sending heartbeat")
-		_, err := http.Get(u)
-		if err != nil {
-			log.Error().Str("monitoring", "heartbeat").Msg(err.Error())
+		ping(client, http.MethodGet, u, "")
+	}
+
+	if len(conf.Checks) == 0 {
+		return
+	}
+
+	run, _ := report.Latest(num, job)
+	body := fmt.Sprintf("backup took %v, %d succeeded, %d failed, %d skipped\n",
+		duration.Round(time.Millisecond), run.Succeeded, run.Failed, run.Skipped)
+
+	for _, check := range conf.Checks {
+		if run.Failed > 0 && check.Fail {
+			ping(client, http.MethodPost, checkURL(check.URL, "/fail", rid), excerpt(body, run))
+		} else {
+			ping(client, http.MethodPost, checkURL(check.URL, "", rid), body)
+		}
+	}
+}
+
+func ping(client *http.Client, method, u, body string) {
+	log.Info().Str("url", u).Msg("sending heartbeat")
+
+	req, err := http.NewRequest(method, u, strings.NewReader(body))
+	if err != nil {
+		log.Error().Str("monitoring", "heartbeat").Msg(err.Error())
+		return
+	}
+
+	if body != "" {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Error().Str("monitoring", "heartbeat").Msg(err.Error())
+		return
+	}
+
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		log.Error().Str("monitoring", "heartbeat").Str("url", u).Msgf("received status %d", res.StatusCode)
+	}
+}
+
+// excerpt appends the errors and logs of the failed repositories to body.
+func excerpt(body string, run report.Run) string {
+	b := strings.Builder{}
+	b.WriteString(body)
+
+	for _, result := range run.Results {
+		if result.Status != report.Failure {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n%s/%s", result.Owner, result.Name)
+		if result.Destination != "" {
+			fmt.Fprintf(&b, " to %s", result.Destination)
+		}
+
+		fmt.Fprintf(&b, ": %s\n", result.Error)
+		for _, line := range result.Log {
+			b.WriteString(line + "\n")
 		}
 	}
+
+	text := b.String()
+	if len(text) > maxExcerpt {
+		text = text[:maxExcerpt] + "\n[truncated]"
+	}
+
+	return text
+}
+
+// checkURL joins suffix onto the path of the check url and adds the run id to its query, which lets
+// healthchecks.io match the start and the end of a run.
+func checkURL(raw, suffix, rid string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Conf.Validate reports invalid urls, the request fails with the error
+		return raw
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + suffix
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/") + suffix
+	}
+
+	if rid != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += "rid=" + url.QueryEscape(rid)
+	}
+
+	return u.String()
 }
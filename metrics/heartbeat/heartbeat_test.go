@@ -0,0 +1,75 @@
+package heartbeat
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cooperspencer/gickup/report"
+	"github.com/cooperspencer/gickup/types"
+)
+
+func TestHeartbeat(t *testing.T) {
+	mu := sync.Mutex{}
+	requests := []string{}
+	bodies := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		bodies[r.URL.Path] = string(body)
+
+		if r.URL.Path != "/plain" && len(r.URL.Query().Get("rid")) != 36 {
+			t.Errorf("%s has no run id", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	conf := types.HeartbeatConfig{
+		URLs: []string{server.URL + "/plain"},
+		Checks: []types.HeartbeatCheck{
+			{URL: server.URL + "/both", Start: true, Fail: true},
+			{URL: server.URL + "/end"},
+		},
+	}
+
+	report.Start(3, 0)
+	Start(conf, 3, 0, "0f8fad5b-d9cb-469f-a165-70867728950e")
+	report.Add(3, 0, report.Result{Owner: "me", Name: "broken", Status: report.Failure, Error: "timeout", Log: []string{"ERR timeout"}})
+	report.Finish(3, 0)
+	Send(conf, 3, 0, time.Second)
+
+	expected := "GET /both/start,GET /plain,POST /both/fail,POST /end"
+	if strings.Join(requests, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(requests, ","))
+	}
+
+	if !strings.Contains(bodies["/both/fail"], "me/broken: timeout\nERR timeout") {
+		t.Errorf("the fail body has no excerpt: %q", bodies["/both/fail"])
+	}
+
+	if !strings.HasPrefix(bodies["/end"], "backup took 1s, 0 succeeded, 1 failed") {
+		t.Errorf("unexpected body %q", bodies["/end"])
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, c := range []struct {
+		url, suffix, rid, expected string
+	}{
+		{"https://hc-ping.com/uuid/", "/start", "", "https://hc-ping.com/uuid/start"},
+		{"https://hc-ping.com/uuid", "", "rid", "https://hc-ping.com/uuid?rid=rid"},
+		{"https://example.com/ping?key=x", "/fail", "rid", "https://example.com/ping/fail?key=x&rid=rid"},
+	} {
+		if u := checkURL(c.url, c.suffix, c.rid); u != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, u)
+		}
+	}
+}
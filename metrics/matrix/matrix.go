@@ -0,0 +1,28 @@
+package matrix
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cooperspencer/gickup/metrics/push"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// Notify sends the notification to a matrix room with the client-server api of the homeserver.
+func Notify(notification types.Notification, config types.PushConfig) error {
+	// the homeserver drops messages with a transaction id it has seen before, so every message gets a new one
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/gickup%d",
+		strings.TrimSuffix(config.Url, "/"), url.PathEscape(config.Room), time.Now().UnixNano())
+
+	payload := map[string]string{
+		"msgtype": "m.text",
+		"body":    fmt.Sprintf("%s\n%s", notification.Title, notification.Message),
+	}
+
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", config.Token)}
+
+	return push.JSON(http.MethodPut, endpoint, payload, headers)
+}
@@ -0,0 +1,44 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cooperspencer/gickup/types"
+)
+
+func TestNotify(t *testing.T) {
+	paths := []string{}
+	payload := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s, %s", r.Method, r.Header.Get("Authorization"))
+		}
+
+		paths = append(paths, r.URL.EscapedPath())
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	notification := types.Notification{Title: "Backup failed", Message: "me/broken to gitea: timeout"}
+	config := types.PushConfig{Url: server.URL + "/", Room: "!room:example.com", Token: "token"}
+	for i := 0; i < 2; i++ {
+		if err := Notify(notification, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prefix := "/_matrix/client/v3/rooms/%21room:example.com/send/m.room.message/gickup"
+	if len(paths) != 2 || !strings.HasPrefix(paths[0], prefix) || paths[0] == paths[1] {
+		t.Errorf("expected two messages to %s with different transaction ids, got %v", prefix, paths)
+	}
+
+	if payload["msgtype"] != "m.text" || payload["body"] != "Backup failed\nme/broken to gitea: timeout" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+}
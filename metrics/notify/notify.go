@@ -0,0 +1,238 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/cooperspencer/gickup/metrics/discord"
+	"github.com/cooperspencer/gickup/metrics/gotify"
+	"github.com/cooperspencer/gickup/metrics/matrix"
+	"github.com/cooperspencer/gickup/metrics/ntfy"
+	"github.com/cooperspencer/gickup/metrics/slack"
+	"github.com/cooperspencer/gickup/metrics/smtp"
+	"github.com/cooperspencer/gickup/metrics/teams"
+	"github.com/cooperspencer/gickup/metrics/webhook"
+	"github.com/cooperspencer/gickup/report"
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultTitle   = `{{if .Success}}Backup done{{else}}Backup failed{{end}}`
+	defaultMessage = `backup took {{.Duration}}
+{{- if .Failed}}, {{.Failed}} of {{.Total}} backups failed
+{{- range .Failures}}
+{{.Repo}}{{if .Destination}} to {{.Destination}}{{end}}: {{.Error}}
+{{- end}}
+{{- end}}`
+)
+
+// Failure is the failed backup of a repository to a destination.
+type Failure struct {
+	Repo        string `json:"repo"`
+	Destination string `json:"destination,omitempty"`
+	Error       string `json:"error"`
+}
+
+// Result is the backup of a repository to a destination, skipped repositories have a reason.
+type Result struct {
+	Repo        string        `json:"repo"`
+	Destination string        `json:"destination,omitempty"`
+	Status      string        `json:"status"`
+	Duration    time.Duration `json:"duration_ns"`
+	Error       string        `json:"error,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+}
+
+// Summary is what the title and message templates of a notification are executed with. The counts
+// are counts of backups, a backup is a repository to one destination.
+type Summary struct {
+	Config    int           `json:"config"`
+	Duration  time.Duration `json:"duration_ns"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Total     int           `json:"total"`
+	Failures  []Failure     `json:"failures"`
+	Results   []Result      `json:"results"`
+	// Success is true if no backup of the run failed.
+	Success bool `json:"success"`
+	// Changed is true if the run succeeded and the previous one failed or the other way round. The
+	// previous run is kept in memory, the first run only counts as change if it failed.
+	Changed bool `json:"changed"`
+}
+
+// Notifier sends notifications to a service.
+type Notifier interface {
+	Notify(notification types.Notification, config types.PushConfig) error
+}
+
+// NotifierFunc is a function that is a Notifier.
+type NotifierFunc func(types.Notification, types.PushConfig) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(notification types.Notification, config types.PushConfig) error {
+	return f(notification, config)
+}
+
+// Notifiers are the notifiers by the names of their configs in types.PushConfigs.
+var Notifiers = map[string]Notifier{
+	"ntfy":    NotifierFunc(ntfy.Notify),
+	"gotify":  NotifierFunc(gotify.Notify),
+	"webhook": NotifierFunc(webhook.Notify),
+	"slack":   NotifierFunc(slack.Notify),
+	"discord": NotifierFunc(discord.Notify),
+	"matrix":  NotifierFunc(matrix.Notify),
+	"teams":   NotifierFunc(teams.Notify),
+	"smtp":    NotifierFunc(smtp.Notify),
+}
+
+// key identifies a job of a configuration, every job is compared to its own previous run.
+type key struct {
+	num, job int
+}
+
+var (
+	mu       sync.Mutex
+	previous = map[key]bool{}
+)
+
+// Summarize builds the summary of the latest run of the job of the configuration num. Every call
+// compares the run to the one of the previous call.
+func Summarize(num, job int, duration time.Duration) Summary {
+	run, _ := report.Latest(num, job)
+
+	summary := Summary{
+		Config:    num,
+		Duration:  duration,
+		Succeeded: run.Succeeded,
+		Failed:    run.Failed,
+		Skipped:   run.Skipped,
+		Total:     len(run.Results),
+		Failures:  []Failure{},
+		Results:   []Result{},
+		Success:   run.Failed == 0,
+	}
+
+	for _, result := range run.Results {
+		summary.Results = append(summary.Results, Result{
+			Repo:        result.Owner + "/" + result.Name,
+			Destination: result.Destination,
+			Status:      result.Status,
+			Duration:    time.Duration(result.Duration * float64(time.Second)).Round(time.Millisecond),
+			Error:       result.Error,
+			Reason:      result.Reason,
+		})
+
+		if result.Status != report.Failure {
+			continue
+		}
+
+		summary.Failures = append(summary.Failures, Failure{
+			Repo:        result.Owner + "/" + result.Name,
+			Destination: result.Destination,
+			Error:       result.Error,
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the first run is a change only if it failed
+	success, ok := previous[key{num, job}]
+	if !ok {
+		success = true
+	}
+
+	summary.Changed = success != summary.Success
+	previous[key{num, job}] = summary.Success
+
+	return summary
+}
+
+// Wants checks if a notification is sent for the summary, on is always, failure, success or change.
+func Wants(on string, summary Summary) bool {
+	switch strings.ToLower(on) {
+	case types.NotifyFailure:
+		return !summary.Success
+	case types.NotifySuccess:
+		return summary.Success
+	case types.NotifyChange:
+		return summary.Changed
+	default:
+		return true
+	}
+}
+
+// Send sends the notification of the summary with every config that wants it.
+func Send(configs types.PushConfigs, summary Summary) {
+	for name, pushers := range configs.All() {
+		notifier, ok := Notifiers[name]
+		if !ok {
+			log.Warn().Str("push", name).Msg("no notifier")
+			continue
+		}
+
+		for _, pusher := range pushers {
+			if pusher == nil || !Wants(pusher.On, summary) {
+				continue
+			}
+
+			// resolve a copy, so secret references are resolved again on the next run
+			pusher := *pusher
+			pusher.ResolveToken()
+
+			notification, err := Render(pusher, summary)
+			if err != nil {
+				log.Warn().Str("push", name).Err(err).Msg("couldn't render message")
+				continue
+			}
+
+			if err := notifier.Notify(notification, pusher); err != nil {
+				log.Warn().Str("push", name).Err(err).Msg("couldn't send message")
+			}
+		}
+	}
+}
+
+// Render executes the title and message templates of the config with the summary.
+func Render(config types.PushConfig, summary Summary) (types.Notification, error) {
+	notification := types.Notification{Summary: summary}
+
+	title, err := execute("title", config.Title, defaultTitle, summary)
+	if err != nil {
+		return notification, err
+	}
+
+	message, err := execute("message", config.Message, defaultMessage, summary)
+	if err != nil {
+		return notification, err
+	}
+
+	notification.Title = title
+	notification.Message = message
+
+	return notification, nil
+}
+
+func execute(name, text, fallback string, summary Summary) (string, error) {
+	if text == "" {
+		text = fallback
+	}
+
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cooperspencer/gickup/report"
+	"github.com/cooperspencer/gickup/types"
+)
+
+func TestNotify(t *testing.T) {
+	report.Start(7, 0)
+	report.Add(7, 0, report.Result{Owner: "me", Name: "ok", Destination: "local", Status: report.Success})
+	report.Add(7, 0, report.Result{Owner: "me", Name: "broken", Destination: "gitea", Status: report.Failure, Error: "timeout"})
+	report.Finish(7, 0)
+
+	summary := Summarize(7, 0, time.Second)
+	if summary.Success || !summary.Changed || summary.Total != 2 || len(summary.Failures) != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	if Wants(types.NotifySuccess, summary) || !Wants(types.NotifyFailure, summary) || !Wants("", summary) {
+		t.Error("wrong notification decision for a failed run")
+	}
+
+	notification, err := Render(types.PushConfig{}, summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if notification.Title != "Backup failed" {
+		t.Errorf("unexpected title %q", notification.Title)
+	}
+
+	expected := "backup took 1s, 1 of 2 backups failed\nme/broken to gitea: timeout"
+	if notification.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, notification.Message)
+	}
+
+	notification, err = Render(types.PushConfig{Title: "{{.Failed}} failed"}, summary)
+	if err != nil || notification.Title != "1 failed" {
+		t.Errorf("unexpected custom title %q, %v", notification.Title, err)
+	}
+
+	if Summarize(7, 0, time.Second).Changed {
+		t.Error("a second failed run isn't a change")
+	}
+
+	report.Start(7, 1)
+	report.Add(7, 1, report.Result{Owner: "me", Name: "ok", Destination: "local", Status: report.Success})
+	report.Finish(7, 1)
+
+	if Summarize(7, 1, time.Second).Changed {
+		t.Error("a successful job is compared to the failed run of another job")
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	for name := range (types.PushConfigs{}).All() {
+		if _, ok := Notifiers[name]; !ok {
+			t.Errorf("%s has no notifier", name)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var body, header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		header = r.Header.Get("X-Token")
+	}))
+	defer server.Close()
+
+	config := types.PushConfig{
+		Url:     server.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+		Body:    `{"text": {{json .Title}}, "failed": {{.Summary.Failed}}}`,
+	}
+
+	Send(types.PushConfigs{Webhook: []*types.PushConfig{&config}}, Summary{Failed: 2})
+
+	if body != `{"text": "Backup failed", "failed": 2}` || header != "secret" {
+		t.Errorf("unexpected request %q with header %q", body, header)
+	}
+}
@@ -5,18 +5,19 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/cooperspencer/gickup/metrics/push"
 	"github.com/cooperspencer/gickup/types"
 )
 
-func Notify(msg string, config types.PushConfig) error {
+func Notify(notification types.Notification, config types.PushConfig) error {
 	url := config.Url
 
-	payload := strings.NewReader(msg)
+	payload := strings.NewReader(notification.Message)
 
 	req, _ := http.NewRequest("POST", url, payload)
 
 	req.Header.Add("Content-Type", "text/plain")
-	req.Header.Add("Title", "Backup done")
+	req.Header.Add("Title", notification.Title)
 
 	if config.Token != "" {
 		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", config.Token))
@@ -26,7 +27,7 @@ func Notify(msg string, config types.PushConfig) error {
 		return fmt.Errorf("neither user, password and token are set")
 	}
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := push.Client.Do(req)
 
 	if err != nil {
 		return err
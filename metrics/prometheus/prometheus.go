@@ -2,11 +2,14 @@ package prometheus
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/cooperspencer/gickup/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
 	"github.com/rs/zerolog/log"
 )
 
@@ -22,7 +25,7 @@ var CountDestinationsConfigured = promauto.NewGauge(prometheus.GaugeOpts{
 
 var CountReposDiscovered = promauto.NewGaugeVec(prometheus.GaugeOpts{
 	Name: "gickup_repos_discovered",
-	Help: "The count of sources configured",
+	Help: "The count of repositories discovered by a source",
 }, []string{"source_name", "config_number"})
 
 var JobsComplete = promauto.NewCounter(prometheus.CounterOpts{
@@ -60,6 +63,107 @@ var RepoTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
 	Help: "How long did the task take",
 }, []string{"hoster", "repository", "owner", "type", "path"})
 
+// Push pushes the metrics to the pushgateway, grouped by job and instance. The metrics of all
+// configurations are pushed into the same group, every push replaces the metrics of the one before.
+func Push(conf types.PushgatewayConfig) {
+	pusher := push.New(conf.URL, conf.GetJob()).
+		Client(&http.Client{Timeout: 30 * time.Second}).
+		Gatherer(prometheus.DefaultGatherer).
+		Grouping("instance", conf.GetInstance())
+
+	if conf.User != "" {
+		pusher = pusher.BasicAuth(conf.User, conf.GetPassword())
+	}
+
+	if err := pusher.Push(); err != nil {
+		log.Error().
+			Str("monitoring", "pushgateway").
+			Str("url", conf.URL).
+			Msg(err.Error())
+	}
+}
+
+var RepoSizeGrowth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "gickup_repo_size_growth_bytes",
+	Help: "How much the size of the backup on disk grew during the last backup, 0 if it shrank",
+}, []string{"hoster", "repository", "owner", "type", "path"})
+
+var RepoObjects = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "gickup_repo_objects",
+	Help: "The count of git objects of the backup",
+}, []string{"hoster", "repository", "owner", "type", "path"})
+
+var RepoSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "gickup_repo_size_bytes",
+	Help: "The size of the backup on disk",
+}, []string{"hoster", "repository", "owner", "type", "path"})
+
+var RepoLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "gickup_repo_last_success_timestamp_seconds",
+	Help: "When the last backup succeeded as unix timestamp, to alert on stale backups",
+}, []string{"hoster", "repository", "owner", "type", "path"})
+
+var Errors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gickup_errors_total",
+	Help: "The count of failed backups by the class of the error",
+}, []string{"hoster", "type", "class"})
+
+var APIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gickup_api_requests_total",
+	Help: "The count of requests to the apis of the hosters",
+}, []string{"hoster", "code", "method"})
+
+var APIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gickup_api_request_duration_seconds",
+	Help:    "How long the requests to the apis of the hosters took",
+	Buckets: prometheus.DefBuckets,
+}, []string{"hoster"})
+
+// Instrument returns a copy of client, or a new client if it is nil, which counts and times the
+// requests to the api of hoster.
+func Instrument(hoster string, client *http.Client) *http.Client {
+	instrumented := &http.Client{}
+	if client != nil {
+		*instrumented = *client
+	}
+
+	transport := instrumented.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	labels := prometheus.Labels{"hoster": hoster}
+	instrumented.Transport = promhttp.InstrumentRoundTripperCounter(APIRequests.MustCurryWith(labels),
+		promhttp.InstrumentRoundTripperDuration(APIRequestDuration.MustCurryWith(labels), transport))
+
+	return instrumented
+}
+
+// ErrorClass classifies the error message of a failed backup, e.g. as auth or network error.
+func ErrorClass(msg string) string {
+	msg = strings.ToLower(msg)
+	classes := []struct {
+		class string
+		words []string
+	}{
+		{"rate_limit", []string{"rate limit", "429", "too many requests"}},
+		{"auth", []string{"401", "403", "auth", "permission denied", "unauthorized", "forbidden", "credentials"}},
+		{"not_found", []string{"404", "not found", "doesn't exist", "does not exist"}},
+		{"disk", []string{"no space", "not enough space", "disk quota", "read-only file system"}},
+		{"network", []string{"timeout", "connection refused", "connection reset", "no such host", "eof", "dial tcp", "tls"}},
+	}
+
+	for _, c := range classes {
+		for _, word := range c.words {
+			if strings.Contains(msg, word) {
+				return c.class
+			}
+		}
+	}
+
+	return "other"
+}
+
 func Serve(conf types.PrometheusConfig) {
 	log.Info().
 		Str("listenAddr", conf.ListenAddr).
@@ -0,0 +1,64 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cooperspencer/gickup/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPush(t *testing.T) {
+	path, user := "", ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		user, _, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	JobsStarted.Inc()
+	Push(types.PushgatewayConfig{URL: server.URL, Instance: "backup-host", User: "me"})
+
+	expected := "PUT /metrics/job/gickup/instance/backup-host"
+	if path != expected {
+		t.Errorf("expected %s, got %s", expected, path)
+	}
+
+	if user != "me" {
+		t.Errorf("expected basic auth of me, got %q", user)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	res, err := Instrument("test", nil).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if count := testutil.ToFloat64(APIRequests.WithLabelValues("test", "404", "get")); count != 1 {
+		t.Errorf("expected 1 request, got %v", count)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	for msg, class := range map[string]string{
+		"authentication required":                  "auth",
+		"repository not found":                     "not_found",
+		"dial tcp 127.0.0.1:1: connection refused": "network",
+		"not enough space to clone me/big":         "disk",
+		"API rate limit exceeded":                  "rate_limit",
+		"something else":                           "other",
+	} {
+		if got := ErrorClass(msg); got != class {
+			t.Errorf("%q: expected %s, got %s", msg, class, got)
+		}
+	}
+}
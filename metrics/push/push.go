@@ -0,0 +1,53 @@
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Client sends the notifications. Notifications are sent while the backup lock is held, the timeout
+// keeps a hanging service from blocking the following backups.
+var Client = &http.Client{Timeout: 30 * time.Second}
+
+// Send sends body with the headers to url and checks that the response is a success.
+func Send(method, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := Client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("received status %d from %s", res.StatusCode, req.URL.Host)
+	}
+
+	return nil
+}
+
+// JSON sends payload as JSON to url.
+func JSON(method, url string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	all := map[string]string{"Content-Type": "application/json"}
+	for name, value := range headers {
+		all[name] = value
+	}
+
+	return Send(method, url, body, all)
+}
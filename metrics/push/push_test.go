@@ -0,0 +1,25 @@
+package push
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	timeout := Client.Timeout
+	Client.Timeout = 50 * time.Millisecond
+	defer func() { Client.Timeout = timeout }()
+
+	if err := Send(http.MethodPost, server.URL, nil, nil); err == nil {
+		t.Error("a hanging service didn't time out")
+	}
+}
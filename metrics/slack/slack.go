@@ -0,0 +1,18 @@
+package slack
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cooperspencer/gickup/metrics/push"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// Notify posts the notification to a slack incoming webhook.
+func Notify(notification types.Notification, config types.PushConfig) error {
+	payload := map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", notification.Title, notification.Message),
+	}
+
+	return push.JSON(http.MethodPost, config.Url, payload, nil)
+}
@@ -0,0 +1,231 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/cooperspencer/gickup/types"
+)
+
+const (
+	defaultText = `{{.Message}}
+{{range .Summary.Results}}
+{{.Repo}}{{if .Destination}} to {{.Destination}}{{end}}: {{.Status}}
+{{- if .Error}}, {{.Error}}{{end}}{{if .Reason}}, {{.Reason}}{{end}}
+{{- end}}
+`
+	defaultHTML = `<html>
+<body>
+<p>{{range $i, $line := lines .Message}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Repository</th><th>Destination</th><th>Status</th><th>Duration</th><th>Details</th></tr>
+{{- range .Summary.Results}}
+<tr><td>{{.Repo}}</td><td>{{.Destination}}</td><td>{{.Status}}</td><td>{{.Duration}}</td><td>{{.Error}}{{.Reason}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`
+)
+
+var timeout = 30 * time.Second
+
+// Notify sends the notification as email with a text and a html part.
+func Notify(notification types.Notification, config types.PushConfig) error {
+	text, html, err := render(notification, config.Body)
+	if err != nil {
+		return err
+	}
+
+	message, err := compose(notification.Title, text, html, config)
+	if err != nil {
+		return err
+	}
+
+	return send(message, config)
+}
+
+func render(notification types.Notification, body string) ([]byte, []byte, error) {
+	text := bytes.Buffer{}
+	if err := textTemplate.Must(textTemplate.New("text").Parse(defaultText)).Execute(&text, notification); err != nil {
+		return nil, nil, err
+	}
+
+	if body == "" {
+		body = defaultHTML
+	}
+
+	tmpl, err := template.New("body").Funcs(types.MailFuncs).Parse(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	html := bytes.Buffer{}
+	if err := tmpl.Execute(&html, notification); err != nil {
+		return nil, nil, err
+	}
+
+	return text.Bytes(), html.Bytes(), nil
+}
+
+func compose(subject string, text, html []byte, config types.PushConfig) ([]byte, error) {
+	message := bytes.Buffer{}
+	body := multipart.NewWriter(&message)
+
+	headers := []string{
+		"From: " + config.From,
+		"To: " + strings.Join(config.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", body.Boundary()),
+	}
+
+	message.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain", text},
+		{"text/html", html},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return message.Bytes(), nil
+}
+
+func send(message []byte, config types.PushConfig) error {
+	u, err := url.Parse(config.Url)
+	if err != nil {
+		return err
+	}
+
+	encryption := strings.ToLower(config.TLS)
+	if encryption == "" {
+		encryption = types.SMTPStartTLS
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{
+			types.SMTPStartTLS: "587",
+			types.SMTPImplicit: "465",
+			types.SMTPNone:     "25",
+		}[encryption]
+	}
+
+	host := u.Hostname()
+	addr := net.JoinHostPort(host, port)
+	tlsConfig := &tls.Config{ServerName: host}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	if encryption == types.SMTPImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if encryption == types.SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s doesn't support STARTTLS", addr)
+		}
+
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if config.User != "" {
+		// PlainAuth refuses to send the password without tls, except to localhost
+		if err := client.Auth(smtp.PlainAuth("", config.User, config.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+
+	for _, to := range config.To {
+		recipient, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return fmt.Errorf("%s: %s", to, err.Error())
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, bytes.NewReader(message)); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
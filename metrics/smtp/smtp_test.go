@@ -0,0 +1,123 @@
+package smtp_test
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cooperspencer/gickup/metrics/notify"
+	"github.com/cooperspencer/gickup/metrics/smtp"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// serve is a smtp stand-in that accepts one message and returns the commands and the data.
+func serve(listener net.Listener, received chan<- []string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	lines := []string{}
+
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			break
+		}
+
+		lines = append(lines, line)
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO":
+			text.PrintfLine("250-localhost")
+			text.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			text.PrintfLine("235 authenticated")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, _ := text.ReadDotLines()
+			lines = append(lines, data...)
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			received <- lines
+			return
+		default:
+			text.PrintfLine("250 ok")
+		}
+	}
+
+	received <- lines
+}
+
+func TestNotify(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go serve(listener, received)
+
+	summary := notify.Summary{
+		Results: []notify.Result{{Repo: "me/broken", Destination: "gitea", Status: "failure", Duration: time.Second, Error: "timeout"}},
+	}
+
+	config := types.PushConfig{
+		Url:      "smtp://" + listener.Addr().String(),
+		TLS:      types.SMTPNone,
+		User:     "user",
+		Password: "password",
+		From:     "gickup <gickup@example.com>",
+		To:       []string{"a@example.com", "b@example.com"},
+	}
+
+	notification := types.Notification{Title: "Backup failed", Message: "backup took 1s", Summary: summary}
+	if err := smtp.Notify(notification, config); err != nil {
+		t.Fatal(err)
+	}
+
+	session := strings.Join(<-received, "\n")
+	for _, expected := range []string{
+		"AUTH PLAIN",
+		"MAIL FROM:<gickup@example.com>",
+		"RCPT TO:<a@example.com>",
+		"RCPT TO:<b@example.com>",
+		"Subject: Backup failed",
+		"Content-Type: text/plain; charset=utf-8",
+		"me/broken to gitea: failure, timeout",
+		"Content-Type: text/html; charset=utf-8",
+		"<td>me/broken</td>",
+	} {
+		if !strings.Contains(session, expected) {
+			t.Errorf("%q is missing in the session:\n%s", expected, session)
+		}
+	}
+}
+
+func TestNotifyRequiresStartTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go serve(listener, make(chan []string, 1))
+
+	config := types.PushConfig{
+		Url:  "smtp://" + listener.Addr().String(),
+		From: "gickup@example.com",
+		To:   []string{"a@example.com"},
+	}
+
+	if err := smtp.Notify(types.Notification{Summary: notify.Summary{}}, config); err == nil {
+		t.Error("sent the message without STARTTLS")
+	}
+}
@@ -0,0 +1,23 @@
+package teams
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cooperspencer/gickup/metrics/push"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// Notify posts the notification as message card to a microsoft teams incoming webhook.
+func Notify(notification types.Notification, config types.PushConfig) error {
+	payload := map[string]string{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  notification.Title,
+		"title":    notification.Title,
+		// the text is markdown, single line breaks are ignored
+		"text": strings.ReplaceAll(notification.Message, "\n", "\n\n"),
+	}
+
+	return push.JSON(http.MethodPost, config.Url, payload, nil)
+}
@@ -0,0 +1,37 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cooperspencer/gickup/types"
+)
+
+func TestNotify(t *testing.T) {
+	payload := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s, %s", r.Method, r.Header.Get("Content-Type"))
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	notification := types.Notification{Title: "Backup failed", Message: "1 of 2 backups failed\nme/broken to gitea: timeout"}
+	if err := Notify(notification, types.PushConfig{Url: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload["@type"] != "MessageCard" || payload["title"] != "Backup failed" || payload["summary"] != "Backup failed" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+
+	if expected := "1 of 2 backups failed\n\nme/broken to gitea: timeout"; payload["text"] != expected {
+		t.Errorf("expected the text %q, got %q", expected, payload["text"])
+	}
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/cooperspencer/gickup/metrics/push"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// Notify sends the notification to a webhook. Without a body template, the title, message and
+// summary are sent as JSON.
+func Notify(notification types.Notification, config types.PushConfig) error {
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	if config.Body == "" {
+		return push.JSON(method, config.Url, notification, config.Headers)
+	}
+
+	tmpl, err := template.New("body").Funcs(types.BodyFuncs).Parse(config.Body)
+	if err != nil {
+		return err
+	}
+
+	body := bytes.Buffer{}
+	if err := tmpl.Execute(&body, notification); err != nil {
+		return err
+	}
+
+	return push.Send(method, config.Url, body.Bytes(), config.Headers)
+}
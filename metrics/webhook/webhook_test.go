@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cooperspencer/gickup/types"
+)
+
+func TestNotify(t *testing.T) {
+	var method, contentType, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, contentType, auth, body = r.Method, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), string(data)
+	}))
+	defer server.Close()
+
+	notification := types.Notification{Title: "Backup failed", Message: "1 of 2 backups failed", Summary: map[string]int{"failed": 1}}
+	config := types.PushConfig{Url: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+
+	if err := Notify(notification, config); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := types.Notification{}
+	if err := json.Unmarshal([]byte(body), &sent); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPost || contentType != "application/json" || auth != "Bearer token" {
+		t.Errorf("unexpected request %s, %s, %s", method, contentType, auth)
+	}
+
+	if sent.Title != notification.Title || sent.Message != notification.Message || sent.Summary == nil {
+		t.Errorf("unexpected payload %s", body)
+	}
+
+	config.Method = "put"
+	config.Body = `{"text": {{ json .Message }}}`
+	if err := Notify(notification, config); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut || body != `{"text": "1 of 2 backups failed"}` || auth != "Bearer token" {
+		t.Errorf("unexpected request %s with body %s", method, body)
+	}
+}
+
+func TestNotifyFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if err := Notify(types.Notification{}, types.PushConfig{Url: server.URL}); err == nil {
+		t.Error("expected an error for a rejected notification")
+	}
+
+	if err := Notify(types.Notification{}, types.PushConfig{Url: server.URL, Body: "{{ .Missing }"}); err == nil {
+		t.Error("expected an error for an invalid body")
+	}
+}
@@ -2,8 +2,10 @@ package onedev
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/cooperspencer/gickup/metrics/prometheus"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/cooperspencer/onedev"
 	"github.com/rs/zerolog/log"
@@ -13,6 +15,13 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 	ran := false
 	repos := []types.Repo{}
 
+	if len(conf.Source.OneDev) > 0 {
+		// the onedev client always uses http.DefaultClient, it is instrumented while the sources are listed
+		defaultClient := http.DefaultClient
+		http.DefaultClient = prometheus.Instrument("onedev", defaultClient)
+		defer func() { http.DefaultClient = defaultClient }()
+	}
+
 	for _, repo := range conf.Source.OneDev {
 		ran = true
 		if repo.URL == "" {
@@ -25,9 +34,7 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				Str("url", repo.URL).
 				Msg(err.Error())
 		}
-		include := types.GetMap(repo.Include)
-		exclude := types.GetMap(repo.Exclude)
-		excludeorgs := types.GetMap(repo.ExcludeOrgs)
+		sel := repo.Selector()
 
 		log.Info().
 			Str("stage", "onedev").
@@ -41,7 +48,16 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 		client := &onedev.Client{}
 
 		if repo.Token != "" || repo.TokenFile != "" {
-			client = onedev.NewClientWithToken(repo.URL, repo.GetToken())
+			token, err := repo.GetToken()
+			if err != nil {
+				log.Error().
+					Str("stage", "onedev").
+					Str("url", repo.URL).
+					Msg(err.Error())
+				continue
+			}
+
+			client = onedev.NewClientWithToken(repo.URL, token)
 		} else {
 			if repo.Password != "" {
 				client = onedev.NewClient(repo.URL, repo.Username, repo.Password)
@@ -84,15 +100,6 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 		}
 
 		for _, r := range userrepos {
-			if len(repo.Include) > 0 {
-				if !include[r.Name] {
-					continue
-				}
-				if exclude[r.Name] {
-					continue
-				}
-			}
-
 			urls, err := client.GetCloneUrl(r.ID)
 			if err != nil {
 				log.Error().
@@ -112,21 +119,8 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				defaultbranch = "main"
 			}
 
-			options := onedev.CommitQueryOptions{Query: fmt.Sprintf("branch(%s)", defaultbranch)}
-			commits, err := client.GetCommits(r.ID, &options)
-			if len(commits) > 0 {
-				commit, err := client.GetCommit(r.ID, commits[0])
-				if err != nil {
-					log.Error().
-						Str("stage", "onedev").
-						Str("url", repo.URL).
-						Msgf("can't get latest commit for %s", defaultbranch)
-				} else {
-					lastactive := time.UnixMicro(commit.Author.When)
-					if time.Since(lastactive) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-						continue
-					}
-				}
+			if !repo.Accept("onedev", sel, projectMeta(client, r, repo.User, defaultbranch, repo)) {
+				continue
 			}
 
 			repos = append(repos, types.Repo{
@@ -159,7 +153,7 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 						Str("url", repo.URL).
 						Msgf("couldn't get group with id %d", membership.GroupID)
 				}
-				if !excludeorgs[group.Name] {
+				if sel.OrgSelected(group.Name) {
 					repo.IncludeOrgs = append(repo.IncludeOrgs, group.Name)
 				}
 			}
@@ -196,6 +190,10 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 						defaultbranch = "main"
 					}
 
+					if !repo.Accept("onedev", sel, projectMeta(client, r, org, defaultbranch, repo)) {
+						continue
+					}
+
 					repos = append(repos, types.Repo{
 						Name:          r.Name,
 						URL:           urls.HTTP,
@@ -214,3 +212,32 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 
 	return repos, ran
 }
+
+// projectMeta returns the metadata of the project the filters are evaluated against,
+// the last activity is the time of the latest commit on the default branch.
+func projectMeta(client *onedev.Client, r onedev.Project, owner, defaultbranch string, repo types.GenRepo) types.RepoMeta {
+	meta := types.RepoMeta{
+		Hoster:       types.GetHost(repo.URL),
+		Name:         r.Name,
+		Owner:        owner,
+		Fork:         r.ForkedFromID != 0,
+		Created:      r.CreateDate,
+		LastActivity: r.UpdateDate,
+	}
+
+	options := onedev.CommitQueryOptions{Query: fmt.Sprintf("branch(%s)", defaultbranch)}
+	commits, _ := client.GetCommits(r.ID, &options)
+	if len(commits) > 0 {
+		commit, err := client.GetCommit(r.ID, commits[0])
+		if err != nil {
+			log.Error().
+				Str("stage", "onedev").
+				Str("url", repo.URL).
+				Msgf("can't get latest commit for %s", defaultbranch)
+		} else {
+			meta.LastActivity = time.UnixMicro(commit.Author.When)
+		}
+	}
+
+	return meta
+}
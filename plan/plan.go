@@ -0,0 +1,95 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+)
+
+// Actions a backup takes for a repository.
+const (
+	Clone        = "clone"
+	Fetch        = "fetch"
+	CreateMirror = "create mirror"
+	CreateOrg    = "create org"
+	Sync         = "sync"
+	Prune        = "prune snapshot"
+	Skip         = "skip"
+)
+
+// Step is what a backup would do with a repository, e.g. clone it to a local destination.
+type Step struct {
+	Source      string `json:"source"`
+	Repo        string `json:"repo"`
+	Destination string `json:"destination,omitempty"`
+	Action      string `json:"action"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	steps   []Step
+)
+
+// Enable makes Add record the steps, it is enabled for dry-runs.
+func Enable() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	enabled = true
+}
+
+// Add records a step of the plan.
+func Add(step Step) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if enabled {
+		steps = append(steps, step)
+	}
+}
+
+// Take returns the recorded steps and starts a new plan.
+func Take() []Step {
+	mu.Lock()
+	defer mu.Unlock()
+
+	taken := steps
+	steps = nil
+
+	return taken
+}
+
+// Write writes the steps as table or json.
+func Write(w io.Writer, steps []Step, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if steps == nil {
+			steps = []Step{}
+		}
+
+		return encoder.Encode(steps)
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SOURCE\tREPOSITORY\tDESTINATION\tACTION\tREASON")
+
+		for _, step := range steps {
+			destination := step.Destination
+			if destination == "" {
+				destination = "-"
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", step.Source, step.Repo, destination, step.Action, step.Reason)
+		}
+
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s, use table or json", format)
+	}
+}
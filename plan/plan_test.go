@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	Add(Step{Source: "github", Repo: "someone/ignored", Action: Skip})
+	if len(Take()) != 0 {
+		t.Error("steps were recorded before Enable")
+	}
+
+	Enable()
+	Add(Step{Source: "github.com", Repo: "cooperspencer/gickup", Destination: "local /backup", Action: Clone})
+	Add(Step{Source: "github", Repo: "cooperspencer/old", Action: Skip, Reason: "archived"})
+
+	steps := Take()
+	if len(steps) != 2 || len(Take()) != 0 {
+		t.Fatalf("expected 2 steps once, got %v", steps)
+	}
+
+	table := bytes.Buffer{}
+	if err := Write(&table, steps, "table"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], "skip") || !strings.HasSuffix(lines[2], "archived") {
+		t.Errorf("unexpected table:\n%s", table.String())
+	}
+
+	out := bytes.Buffer{}
+	if err := Write(&out, steps, "json"); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := []Step{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1].Reason != "archived" {
+		t.Errorf("unexpected json %s: %v", out.String(), err)
+	}
+
+	if err := Write(&out, steps, "yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
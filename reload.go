@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cooperspencer/gickup/logger"
+	"github.com/cooperspencer/gickup/types"
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// reloadInterval is how often the configfiles are checked for changes.
+const reloadInterval = 5 * time.Second
+
+// activeConfs holds the configurations which are scheduled and their cron entries.
+type activeConfs struct {
+	mu      sync.RWMutex
+	confs   []*types.Conf
+	entries []cron.EntryID
+}
+
+var active = &activeConfs{}
+
+func (a *activeConfs) get() []*types.Conf {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.confs
+}
+
+// swap replaces the active configurations and returns the cron entries of the previous ones.
+func (a *activeConfs) swap(confs []*types.Conf, entries []cron.EntryID) []cron.EntryID {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	old := a.entries
+	a.confs = confs
+	a.entries = entries
+
+	return old
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(configfiles []string) map[string]fileState {
+	states := map[string]fileState{}
+	for _, f := range configfiles {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+
+		states[f] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return states
+}
+
+// watchConfig reloads the configfiles when one of them changes or gickup receives SIGHUP.
+// Included files are only reloaded on SIGHUP or a change of the configfiles.
+func watchConfig(c *cron.Cron, configfiles []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	states := stat(configfiles)
+
+	for {
+		select {
+		case <-hup:
+			logger.Background().Info().
+				Str("stage", "reload").
+				Msg("received SIGHUP, reloading configuration")
+			states = stat(configfiles)
+			reload(c, configfiles)
+		case <-ticker.C:
+			current := stat(configfiles)
+			if reflect.DeepEqual(states, current) {
+				continue
+			}
+
+			states = current
+			logger.Background().Info().
+				Str("stage", "reload").
+				Msg("configuration changed, reloading")
+			reload(c, configfiles)
+		}
+	}
+}
+
+// reload reads and validates the configfiles and schedules them instead of the active configurations.
+// If a configfile can't be read or isn't valid, the active configurations are kept.
+func reload(c *cron.Cron, configfiles []string) {
+	confs := []*types.Conf{}
+	valid := true
+
+	for _, f := range configfiles {
+		loaded, err := loadConfigFile(f, false)
+		if err != nil {
+			logger.Background().Error().
+				Str("stage", "reload").
+				Str("file", f).
+				Msg(err.Error())
+			valid = false
+
+			continue
+		}
+
+		for num, conf := range loaded {
+			for _, err := range conf.Validate() {
+				logger.Background().Error().
+					Str("stage", "reload").
+					Str("file", f).
+					Int("config", num).
+					Msg(err.Error())
+				valid = false
+			}
+		}
+
+		confs = append(confs, loaded...)
+	}
+
+	if valid && len(confs) == 0 {
+		logger.Background().Error().
+			Str("stage", "reload").
+			Msg("no configuration found")
+		valid = false
+	}
+
+	if !valid {
+		logger.Background().Warn().
+			Str("stage", "reload").
+			Msg("keeping the current configuration")
+
+		return
+	}
+
+	if confs[0].Log.Timeformat == "" {
+		confs[0].Log.Timeformat = timeFormat()
+	}
+
+	if err := inheritCron(confs); err != nil {
+		logger.Background().Error().
+			Str("stage", "reload").
+			Msg(err.Error())
+		logger.Background().Warn().
+			Str("stage", "reload").
+			Msg("keeping the current configuration")
+
+		return
+	}
+
+	changes := diffConfs(active.get(), confs)
+	if len(changes) == 0 {
+		logger.Background().Info().
+			Str("stage", "reload").
+			Msg("configuration didn't change")
+
+		return
+	}
+
+	for _, change := range changes {
+		logger.Background().Info().
+			Str("stage", "reload").
+			Msg(change)
+
+		if strings.HasSuffix(change, " log") || strings.HasSuffix(change, " webhook") {
+			logger.Background().Warn().
+				Str("stage", "reload").
+				Msg("changes of the log and webhook settings take effect after a restart")
+		}
+	}
+
+	if err := schedule(c, confs, false); err != nil {
+		logger.Background().Error().
+			Str("stage", "reload").
+			Msg(err.Error())
+		logger.Background().Warn().
+			Str("stage", "reload").
+			Msg("keeping the current configuration")
+	}
+}
+
+// settings maps a description of every setting of conf to its yaml representation.
+func settings(conf *types.Conf) map[string]string {
+	list := map[string]string{}
+
+	add := func(key string, value interface{}) {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			data = []byte(fmt.Sprint(value))
+		}
+
+		// the same repository may be configured more than once
+		k := key
+		for i := 2; ; i++ {
+			if _, ok := list[k]; !ok {
+				break
+			}
+			k = fmt.Sprintf("%s (%d)", key, i)
+		}
+
+		list[k] = string(data)
+	}
+
+	add("cron", conf.Cron)
+	add("timezone", conf.Timezone)
+	add("jitter", conf.Jitter)
+	add("window", conf.Window)
+	add("log", conf.Log)
+	add("metrics", conf.Metrics)
+	add("webhook", conf.Webhook)
+
+	for section, value := range map[string]interface{}{"source": conf.Source, "destination": conf.Destination} {
+		v := reflect.ValueOf(value)
+		for i := 0; i < v.NumField(); i++ {
+			hoster := v.Type().Field(i).Tag.Get("yaml")
+			entries := v.Field(i)
+
+			for j := 0; j < entries.Len(); j++ {
+				entry := entries.Index(j).Interface()
+				name := ""
+				if d, ok := entry.(interface{ Describe() string }); ok {
+					name = d.Describe()
+				}
+
+				add(fmt.Sprintf("%s %s %s", section, hoster, name), entry)
+			}
+		}
+	}
+
+	return list
+}
+
+// diffConfs describes what changed between the configurations old and new.
+func diffConfs(old, new []*types.Conf) []string {
+	changes := []string{}
+
+	for num := 0; num < len(old) || num < len(new); num++ {
+		switch {
+		case num >= len(old):
+			changes = append(changes, fmt.Sprintf("config %d: added", num))
+		case num >= len(new):
+			changes = append(changes, fmt.Sprintf("config %d: removed", num))
+		}
+
+		before, after := map[string]string{}, map[string]string{}
+		if num < len(old) {
+			before = settings(old[num])
+		}
+		if num < len(new) {
+			after = settings(new[num])
+		}
+
+		keys := []string{}
+		for key := range before {
+			keys = append(keys, key)
+		}
+		for key := range after {
+			if _, ok := before[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			b, inBefore := before[key]
+			a, inAfter := after[key]
+
+			switch {
+			case !inBefore:
+				if num < len(old) || isRepoSetting(key) {
+					changes = append(changes, fmt.Sprintf("config %d: added %s", num, key))
+				}
+			case !inAfter:
+				if num < len(new) || isRepoSetting(key) {
+					changes = append(changes, fmt.Sprintf("config %d: removed %s", num, key))
+				}
+			case a != b:
+				changes = append(changes, fmt.Sprintf("config %d: changed %s", num, key))
+			}
+		}
+	}
+
+	return changes
+}
+
+// isRepoSetting checks if key describes a source or destination.
+func isRepoSetting(key string) bool {
+	return strings.HasPrefix(key, "source ") || strings.HasPrefix(key, "destination ")
+}
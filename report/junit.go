@@ -0,0 +1,90 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report to path as JUnit XML, every repository and destination is a test case.
+func (r Report) WriteJUnit(path string) error {
+	suites := junitSuites{}
+	for _, run := range r.Runs {
+		name := fmt.Sprintf("config %d", run.Config)
+		if run.Job > 0 {
+			name += fmt.Sprintf(" job %d", run.Job)
+		}
+
+		suite := junitSuite{
+			Name:      name,
+			Tests:     len(run.Results),
+			Failures:  run.Failed,
+			Skipped:   run.Skipped,
+			Time:      run.Duration,
+			Timestamp: run.Started.Format("2006-01-02T15:04:05"),
+		}
+
+		for _, result := range run.Results {
+			name := result.Owner + "/" + result.Name
+			if result.Destination != "" {
+				name += " to " + result.Destination
+			}
+
+			c := junitCase{ClassName: result.Hoster, Name: name, Time: result.Duration}
+			switch result.Status {
+			case Failure:
+				c.Failure = &junitMessage{Message: result.Error, Body: strings.Join(result.Log, "\n")}
+			case Skipped:
+				c.Skipped = &junitMessage{Message: result.Reason}
+			}
+
+			suite.Cases = append(suite.Cases, c)
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Time += suite.Time
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o644)
+}
@@ -0,0 +1,178 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of a result.
+const (
+	Success = "success"
+	Failure = "failure"
+	Skipped = "skipped"
+)
+
+// Result is the outcome of the backup of a repository to a destination. Repositories skipped by the
+// filters of their source have no destination.
+type Result struct {
+	Hoster      string   `json:"hoster"`
+	Owner       string   `json:"owner"`
+	Name        string   `json:"name"`
+	Destination string   `json:"destination,omitempty"`
+	Status      string   `json:"status"`
+	Duration    float64  `json:"duration_seconds"`
+	Bytes       int64    `json:"bytes_transferred"`
+	Size        int64    `json:"size"`
+	Error       string   `json:"error,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	Log         []string `json:"log,omitempty"`
+}
+
+// Run is a backup run of a job of a configuration.
+type Run struct {
+	Config    int       `json:"config"`
+	Job       int       `json:"job"`
+	Started   time.Time `json:"started"`
+	Duration  float64   `json:"duration_seconds"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Skipped   int       `json:"skipped"`
+	Results   []Result  `json:"results"`
+}
+
+// Report holds the latest run of every job.
+type Report struct {
+	Runs []Run `json:"runs"`
+}
+
+// key identifies a job, configurations with crons of their own for sources or destinations are split into several.
+type key struct {
+	num, job int
+}
+
+var (
+	mu     sync.Mutex
+	runs   = map[key]*Run{}
+	failed bool
+)
+
+func run(num, job int) *Run {
+	r, ok := runs[key{num, job}]
+	if !ok {
+		r = &Run{Config: num, Job: job, Started: time.Now(), Results: []Result{}}
+		runs[key{num, job}] = r
+	}
+
+	return r
+}
+
+// Start begins a new run of the job of the configuration num.
+func Start(num, job int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(runs, key{num, job})
+	run(num, job)
+}
+
+// Add adds the result to the run of the job of the configuration num.
+func Add(num, job int, result Result) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := run(num, job)
+	r.Results = append(r.Results, result)
+
+	switch result.Status {
+	case Success:
+		r.Succeeded++
+	case Failure:
+		r.Failed++
+		failed = true
+	case Skipped:
+		r.Skipped++
+	}
+}
+
+// Finish ends the run of the job of the configuration num.
+func Finish(num, job int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := run(num, job)
+	r.Duration = time.Since(r.Started).Seconds()
+}
+
+// Failed checks if the backup of any repository failed since gickup started.
+func Failed() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return failed
+}
+
+// Get returns a copy of the report.
+func Get() Report {
+	mu.Lock()
+	defer mu.Unlock()
+
+	report := Report{Runs: []Run{}}
+	for _, r := range runs {
+		run := *r
+		run.Results = append([]Result{}, r.Results...)
+		report.Runs = append(report.Runs, run)
+	}
+
+	sort.Slice(report.Runs, func(i, j int) bool {
+		if report.Runs[i].Config != report.Runs[j].Config {
+			return report.Runs[i].Config < report.Runs[j].Config
+		}
+
+		return report.Runs[i].Job < report.Runs[j].Job
+	})
+
+	return report
+}
+
+// Latest returns a copy of the latest run of the job of the configuration num.
+func Latest(num, job int) (Run, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, ok := runs[key{num, job}]
+	if !ok {
+		return Run{}, false
+	}
+
+	run := *r
+	run.Results = append([]Result{}, r.Results...)
+
+	return run, true
+}
+
+// ErrorOf returns the last error or warning of the log lines of a failed backup.
+func ErrorOf(logs []string) string {
+	for i := len(logs) - 1; i >= 0; i-- {
+		for _, level := range []string{" FTL ", " ERR ", " WRN "} {
+			if idx := strings.Index(logs[i], level); idx >= 0 {
+				return strings.TrimSpace(logs[i][idx+len(level):])
+			}
+		}
+	}
+
+	return ""
+}
+
+// WriteJSON writes the report to path as JSON.
+func (r Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
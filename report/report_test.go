@@ -0,0 +1,72 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	Start(0, 0)
+	Add(0, 0, Result{Hoster: "github.com", Owner: "cooperspencer", Name: "gickup", Destination: "local /backup", Status: Success})
+	Add(0, 0, Result{Hoster: "github", Owner: "cooperspencer", Name: "old", Status: Skipped, Reason: "archived"})
+	if Failed() {
+		t.Error("report failed without failures")
+	}
+
+	logs := []string{
+		"2023-01-01T00:00:00Z INF mirroring gickup stage=gitea",
+		"2023-01-01T00:00:00Z ERR connection refused stage=gitea",
+	}
+	Add(0, 0, Result{Hoster: "github.com", Owner: "cooperspencer", Name: "gickup", Destination: "gitea https://gitea.com", Status: Failure, Error: ErrorOf(logs), Log: logs})
+	Finish(0, 0)
+
+	if !Failed() {
+		t.Error("report didn't fail")
+	}
+
+	report := Get()
+	if len(report.Runs) != 1 || report.Runs[0].Succeeded != 1 || report.Runs[0].Failed != 1 || report.Runs[0].Skipped != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	if report.Runs[0].Results[2].Error != "connection refused stage=gitea" {
+		t.Errorf("unexpected error %q", report.Runs[0].Results[2].Error)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := report.WriteJUnit(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suites := junitSuites{}
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Skipped != 1 || suites.Suites[0].Cases[2].Failure == nil {
+		t.Errorf("unexpected junit report %s", data)
+	}
+}
+
+func TestReportKeepsJobsApart(t *testing.T) {
+	Start(5, 0)
+	Add(5, 0, Result{Owner: "me", Name: "hourly", Status: Success})
+	Finish(5, 0)
+
+	Start(5, 1)
+	Add(5, 1, Result{Owner: "me", Name: "nightly", Status: Skipped})
+	Finish(5, 1)
+
+	first, _ := Latest(5, 0)
+	second, _ := Latest(5, 1)
+	if len(first.Results) != 1 || first.Succeeded != 1 || len(second.Results) != 1 || second.Job != 1 {
+		t.Errorf("the runs of the jobs overwrote each other: %+v, %+v", first, second)
+	}
+}
@@ -0,0 +1,217 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider resolves a secret reference, the reference is everything after the scheme, e.g. secret/data/gickup#github.
+type Provider interface {
+	Resolve(reference string) (string, error)
+}
+
+// CacheTTL is how long resolved secrets are cached before they are resolved again.
+var CacheTTL = 10 * time.Minute
+
+var providers = map[string]Provider{
+	"vault":   Vault{},
+	"command": Command{},
+	"keyring": Keyring{},
+}
+
+type cached struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cached{}
+)
+
+// RegisterProvider adds a provider for references starting with scheme://.
+func RegisterProvider(scheme string, provider Provider) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	providers[scheme] = provider
+}
+
+func split(value string) (Provider, string, bool) {
+	i := strings.Index(value, "://")
+	if i <= 0 {
+		return nil, "", false
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	provider, ok := providers[value[:i]]
+
+	return provider, value[i+3:], ok
+}
+
+// IsReference checks if value refers to a secret of a provider.
+func IsReference(value string) bool {
+	_, _, ok := split(value)
+
+	return ok
+}
+
+// Resolve returns the secret value refers to, values which are no references are returned as they are.
+// Resolved secrets are cached for CacheTTL and redacted from the logs.
+func Resolve(value string) (string, error) {
+	provider, reference, ok := split(value)
+	if !ok {
+		return value, nil
+	}
+
+	cacheMu.Lock()
+	entry, found := cache[value]
+	cacheMu.Unlock()
+
+	if found && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	secret, err := provider.Resolve(reference)
+	if err != nil {
+		return "", fmt.Errorf("can't resolve %s: %s", value, err.Error())
+	}
+
+	Register(secret)
+
+	cacheMu.Lock()
+	cache[value] = cached{value: secret, expires: time.Now().Add(CacheTTL)}
+	cacheMu.Unlock()
+
+	return secret, nil
+}
+
+// Vault reads secrets from the KV v2 engine of HashiCorp Vault,
+// the address and token are taken from VAULT_ADDR and VAULT_TOKEN.
+// The reference is the path of the secret and the key, e.g. secret/data/gickup#github.
+type Vault struct {
+	Client *http.Client
+}
+
+// Resolve implements Provider.
+func (v Vault) Resolve(reference string) (string, error) {
+	path, key := reference, ""
+	if i := strings.LastIndex(reference, "#"); i >= 0 {
+		path, key = reference[:i], reference[i+1:]
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("no key given, use path#key")
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = "http://127.0.0.1:8200"
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(addr, "/"), strings.TrimPrefix(path, "/")), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Add("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Add("X-Vault-Namespace", namespace)
+	}
+
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("received status %d from vault", res.StatusCode)
+	}
+
+	body := struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}{}
+
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	secret, ok := body.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", key)
+	}
+
+	return fmt.Sprint(secret), nil
+}
+
+// Command runs the reference with the shell and uses its output as secret, e.g. command://pass show github.
+type Command struct{}
+
+// Resolve implements Provider.
+func (Command) Resolve(reference string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", reference)
+	} else {
+		cmd = exec.Command("sh", "-c", reference)
+	}
+
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// Keyring reads secrets from the keyring of the OS, the reference is service/user, e.g. keyring://gickup/github.
+// It uses secret-tool on linux and security on macOS.
+type Keyring struct{}
+
+// Resolve implements Provider.
+func (Keyring) Resolve(reference string) (string, error) {
+	i := strings.LastIndex(reference, "/")
+	if i <= 0 {
+		return "", fmt.Errorf("use service/user")
+	}
+
+	service, user := reference[:i], reference[i+1:]
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", user, "-w")
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "username", user)
+	default:
+		return "", fmt.Errorf("keyring isn't supported on %s", runtime.GOOS)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
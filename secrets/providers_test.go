@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/secret/data/gickup" || r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Write([]byte(`{"data":{"data":{"github":"ghp_vaulttoken"},"metadata":{"version":1}}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	for i := 0; i < 2; i++ {
+		secret, err := Resolve("vault://secret/data/gickup#github")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if secret != "ghp_vaulttoken" {
+			t.Errorf("unexpected secret %s", secret)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("secret wasn't cached, vault was called %d times", calls)
+	}
+
+	if _, err := Resolve("vault://secret/data/gickup#gitlab"); err == nil {
+		t.Error("missing key was resolved")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+
+	secret, err := Command{}.Resolve("echo commandsecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if secret != "commandsecret" {
+		t.Errorf("unexpected secret %q", secret)
+	}
+}
+
+func TestResolveNoReference(t *testing.T) {
+	t.Parallel()
+
+	if value, err := Resolve("https://gitea.com"); err != nil || value != "https://gitea.com" {
+		t.Errorf("value without provider was changed to %q, %v", value, err)
+	}
+}
@@ -0,0 +1,182 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// placeholderRx matches ${ENV} and ${file:/path}, $${...} escapes a placeholder.
+var placeholderRx = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// redacted replaces secrets in log output.
+const redacted = "***"
+
+var (
+	mu      sync.RWMutex
+	secrets = map[string]bool{}
+)
+
+// Register marks value as secret, it will be redacted from the logs.
+func Register(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < 4 {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	secrets[value] = true
+}
+
+// Redact replaces every registered secret in p.
+func Redact(p []byte) []byte {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if len(secrets) == 0 {
+		return p
+	}
+
+	// replace longer secrets first, in case one secret contains another
+	list := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		list = append(list, secret)
+	}
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+
+	for _, secret := range list {
+		p = bytes.ReplaceAll(p, []byte(secret), []byte(redacted))
+	}
+
+	return p
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+// Write implements io.Writer.
+func (r redactWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write(Redact(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// RedactWriter returns a writer which redacts every registered secret before writing to w.
+func RedactWriter(w io.Writer) io.Writer {
+	return redactWriter{w: w}
+}
+
+// Interpolate replaces ${ENV} with the value of the environment variable ENV
+// and ${file:/path} with the content of the file, without trailing newlines. The contents of files
+// are registered as secrets, whatever key they are the value of.
+func Interpolate(value string) (string, error) {
+	var err error
+
+	result := placeholderRx.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		if err != nil {
+			return match
+		}
+
+		var resolved string
+		resolved, err = lookup(placeholderRx.FindStringSubmatch(match)[1])
+
+		return resolved
+	})
+
+	return result, err
+}
+
+func lookup(reference string) (string, error) {
+	if strings.HasPrefix(reference, "file:") {
+		file := strings.TrimPrefix(reference, "file:")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("can't read %s: %s", file, err.Error())
+		}
+
+		value := strings.TrimRight(string(data), "\r\n")
+		Register(value)
+
+		return value, nil
+	}
+
+	value, ok := os.LookupEnv(reference)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", reference)
+	}
+
+	return value, nil
+}
+
+// isSecretKey checks if the values of key have to be redacted from the logs.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+
+	return strings.Contains(key, "token") || strings.Contains(key, "password") || strings.Contains(key, "secret")
+}
+
+// InterpolateNode interpolates every scalar of node. Values of secret keys and values read from files
+// are registered for redaction.
+func InterpolateNode(node *yaml.Node) error {
+	return interpolateNode(node, false)
+}
+
+func interpolateNode(node *yaml.Node, secret bool) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := interpolateNode(n, secret); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := interpolateNode(node.Content[i+1], secret || isSecretKey(node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+
+		value, err := Interpolate(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", node.Line, err.Error())
+		}
+
+		if secret {
+			Register(value)
+		}
+
+		node.Value = value
+		if node.Style == 0 {
+			// numbers and booleans are resolved again, e.g. for maxage, everything else stays
+			// a string, e.g. a token which is null
+			node.Tag = ""
+			switch node.ShortTag() {
+			case "!!int", "!!float", "!!bool":
+			default:
+				node.Tag = "!!str"
+			}
+		}
+	}
+
+	return nil
+}
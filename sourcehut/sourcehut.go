@@ -6,19 +6,21 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"time"
 
+	"github.com/cooperspencer/gickup/metrics/prometheus"
 	"github.com/cooperspencer/gickup/types"
 	"github.com/rs/zerolog/log"
 )
 
+var client = prometheus.Instrument("sourcehut", nil)
+
 // doRequest TODO
 func doRequest(url, token string) ([]byte, error) {
 	req, _ := http.NewRequest("GET", url, nil)
 
 	req.Header.Add("Authorization", fmt.Sprintf("token %s", token))
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := client.Do(req)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -136,7 +138,14 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 
 		apiURL := fmt.Sprintf("%sapi/", repo.URL)
 
-		token := repo.GetToken()
+		token, err := repo.GetToken()
+		if err != nil {
+			log.Error().
+				Str("stage", "sourcehut").
+				Str("url", repo.URL).
+				Msg(err.Error())
+			continue
+		}
 
 		if repo.User == "" {
 			user := User{}
@@ -173,8 +182,7 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 
 		apiURL = fmt.Sprintf("%sapi/%s/repos/", repo.URL, repo.User)
 
-		include := types.GetMap(repo.Include)
-		exclude := types.GetMap(repo.Exclude)
+		sel := repo.Selector()
 
 		repositories, err := getRepos(apiURL, token)
 		if err != nil {
@@ -185,6 +193,11 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 		}
 
 		for _, r := range repositories.Results {
+			if ok, reason := sel.Explain(r.Name, r.Owner.CanonicalName, ""); !ok {
+				types.Skip("sourcehut", types.RepoMeta{Hoster: types.GetHost(repo.URL), Name: r.Name, Owner: r.Owner.CanonicalName}, reason)
+				continue
+			}
+
 			repoURL := fmt.Sprintf("%s%s/%s", repo.URL, repo.User, r.Name)
 			sshURL := fmt.Sprintf("git@%s:%s/%s", types.GetHost(repo.URL), r.Owner.CanonicalName, r.Name)
 
@@ -204,36 +217,59 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				}
 			}
 
+			meta := types.RepoMeta{
+				Hoster:       types.GetHost(repo.URL),
+				Name:         r.Name,
+				Owner:        r.Owner.CanonicalName,
+				Visibility:   r.Visibility,
+				Created:      r.Created,
+				LastActivity: r.Updated,
+			}
+
 			commits, err := getCommits(apiURL, r.Name, token)
 			if err != nil {
 				log.Error().
 					Str("stage", "sourcehut").
 					Str("url", repo.URL).
 					Msg(err.Error())
+			} else if len(commits.Results) > 0 {
+				meta.LastActivity = commits.Results[0].Timestamp
 			} else {
-				if len(commits.Results) > 0 {
-					if time.Since(commits.Results[0].Timestamp) > repo.Filter.LastActivityDuration && repo.Filter.LastActivityDuration != 0 {
-						continue
-					}
-				}
+				meta.Empty = true
+			}
+
+			if !repo.Accept("sourcehut", sel, meta) {
+				continue
 			}
 
-			if include[r.Name] {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           repoURL,
-					SSHURL:        sshURL,
-					Token:         token,
-					Defaultbranch: refs.Results[0].Name,
-					Origin:        repo,
-					Owner:         r.Owner.Name,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Visibility == "private",
-				})
-				if repo.Wiki {
+			repos = append(repos, types.Repo{
+				Name:          r.Name,
+				URL:           repoURL,
+				SSHURL:        sshURL,
+				Token:         token,
+				Defaultbranch: head,
+				Origin:        repo,
+				Owner:         r.Owner.Name,
+				Hoster:        types.GetHost(repo.URL),
+				Description:   r.Description,
+				Private:       r.Visibility == "private",
+			})
+			if repo.Wiki {
+				refs, err := getRefs(apiURL, fmt.Sprintf("%s-docs", r.Name), token)
+				if err != nil {
+					continue
+				}
+				if len(refs.Results) > 0 {
+					head = ""
+					for _, ref := range refs.Results {
+						if strings.HasPrefix("refs/heads/", ref.Name) {
+							head = strings.TrimLeft(ref.Name, "refs/heads/")
+							break
+						}
+					}
+
 					repos = append(repos, types.Repo{
-						Name:          r.Name + ".-docs",
+						Name:          r.Name + "-docs",
 						URL:           repoURL + "-docs",
 						SSHURL:        sshURL + "-docs",
 						Token:         token,
@@ -245,55 +281,6 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 						Private:       r.Visibility == "private",
 					})
 				}
-
-				continue
-			}
-
-			if exclude[r.Name] {
-				continue
-			}
-
-			if len(include) == 0 {
-				repos = append(repos, types.Repo{
-					Name:          r.Name,
-					URL:           repoURL,
-					SSHURL:        sshURL,
-					Token:         token,
-					Defaultbranch: head,
-					Origin:        repo,
-					Owner:         r.Owner.Name,
-					Hoster:        types.GetHost(repo.URL),
-					Description:   r.Description,
-					Private:       r.Visibility == "private",
-				})
-				if repo.Wiki {
-					refs, err := getRefs(apiURL, fmt.Sprintf("%s-docs", r.Name), token)
-					if err != nil {
-						continue
-					}
-					if len(refs.Results) > 0 {
-						head = ""
-						for _, ref := range refs.Results {
-							if strings.HasPrefix("refs/heads/", ref.Name) {
-								head = strings.TrimLeft(ref.Name, "refs/heads/")
-								break
-							}
-						}
-
-						repos = append(repos, types.Repo{
-							Name:          r.Name + "-docs",
-							URL:           repoURL + "-docs",
-							SSHURL:        sshURL + "-docs",
-							Token:         token,
-							Defaultbranch: head,
-							Origin:        repo,
-							Owner:         r.Owner.Name,
-							Hoster:        types.GetHost(repo.URL),
-							Description:   r.Description,
-							Private:       r.Visibility == "private",
-						})
-					}
-				}
 			}
 		}
 	}
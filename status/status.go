@@ -0,0 +1,208 @@
+package status
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog"
+)
+
+// maxLogLines is the amount of log lines kept in memory.
+const maxLogLines = 2000
+
+// maxExcerpt is the amount of log lines kept for a failed repository.
+const maxExcerpt = 20
+
+var ansiRx = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Result holds the state of a repository backup to a destination.
+type Result struct {
+	Config      int
+	Hoster      string
+	Owner       string
+	Name        string
+	Destination string
+	Path        string
+	Success     bool
+	LastRun     time.Time
+	LastSuccess time.Time
+	LastFailure time.Time
+	Duration    time.Duration
+	Size        int64
+	Log         []string
+}
+
+// Key identifies the result.
+func (r Result) Key() string {
+	return fmt.Sprintf("%d|%s|%s|%s|%s|%s", r.Config, r.Hoster, r.Owner, r.Name, r.Destination, r.Path)
+}
+
+var (
+	mu      sync.Mutex
+	results = map[string]*Result{}
+)
+
+// Record stores the outcome of a backup of repo to the given destination.
+func Record(num int, repo types.Repo, destination, path string, success bool, duration time.Duration, size int64, logs []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := Result{
+		Config:      num,
+		Hoster:      repo.Hoster,
+		Owner:       repo.Owner,
+		Name:        repo.Name,
+		Destination: destination,
+		Path:        path,
+	}
+
+	existing, ok := results[r.Key()]
+	if !ok {
+		existing = &r
+		results[r.Key()] = existing
+	}
+
+	now := time.Now()
+	existing.Success = success
+	existing.LastRun = now
+	existing.Duration = duration
+	existing.Size = size
+
+	if success {
+		existing.LastSuccess = now
+		existing.Log = nil
+	} else {
+		existing.LastFailure = now
+		existing.Log = logs
+	}
+}
+
+// Results returns a copy of all recorded results, sorted by config, hoster, owner and name.
+func Results() []Result {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := []Result{}
+	for _, r := range results {
+		list = append(list, *r)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key() < list[j].Key()
+	})
+
+	return list
+}
+
+// LogBuffer keeps the latest log lines in memory, so they can be shown for failed repositories.
+type LogBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	seq   int64
+}
+
+// Logs is the buffer the logger writes into.
+var Logs = &LogBuffer{}
+
+// Write implements io.Writer for zerolog's json output.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	out := bytes.Buffer{}
+	w := zerolog.ConsoleWriter{Out: &out, NoColor: true, TimeFormat: time.RFC3339}
+
+	if _, err := w.Write(p); err != nil {
+		return len(p), nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		b.lines = append(b.lines, ansiRx.ReplaceAllString(line, ""))
+		b.seq++
+	}
+
+	if len(b.lines) > maxLogLines {
+		b.lines = b.lines[len(b.lines)-maxLogLines:]
+	}
+
+	return len(p), nil
+}
+
+// Mark returns the current position in the log.
+func (b *LogBuffer) Mark() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seq
+}
+
+// Since returns the log lines written after mark, limited to the latest lines.
+func (b *LogBuffer) Since(mark int64) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := int(b.seq - mark)
+	if count > len(b.lines) {
+		count = len(b.lines)
+	}
+
+	if count > maxExcerpt {
+		count = maxExcerpt
+	}
+
+	if count <= 0 {
+		return nil
+	}
+
+	lines := make([]string, count)
+	copy(lines, b.lines[len(b.lines)-count:])
+
+	return lines
+}
+
+// DirSize returns the size of all files in path.
+func DirSize(path string) int64 {
+	var size int64
+
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err == nil {
+				size += info.Size()
+			}
+		}
+
+		return nil
+	})
+
+	return size
+}
+
+// Size returns the size of path, or of the file path with one of the suffixes if path doesn't exist.
+// Destinations with keep store the snapshots in path, the size is the sum of all of them.
+func Size(path string, suffixes ...string) int64 {
+	if _, err := os.Stat(path); err == nil {
+		return DirSize(path)
+	}
+
+	for _, suffix := range suffixes {
+		if info, err := os.Stat(path + suffix); err == nil {
+			return info.Size()
+		}
+	}
+
+	return 0
+}
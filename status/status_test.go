@@ -0,0 +1,54 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLogBufferSince(t *testing.T) {
+	t.Parallel()
+
+	b := &LogBuffer{}
+	logger := zerolog.New(b)
+	logger.Info().Msg("before")
+
+	mark := b.Mark()
+	logger.Error().Str("stage", "locally").Msg("\x1b[31mbroken\x1b[0m")
+
+	lines := b.Since(mark)
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(lines))
+	}
+
+	if !strings.Contains(lines[0], "ERR broken stage=locally") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+}
+
+func TestSizeSumsSnapshots(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, size := range map[string]int{"1700000000/HEAD": 10, "1700000100/HEAD": 20, "1700000200.tar.gz": 30} {
+		file := filepath.Join(dir, "gickup", name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(file, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if size := Size(filepath.Join(dir, "gickup"), ".tar.gz"); size != 60 {
+		t.Errorf("expected the size of all snapshots, got %d", size)
+	}
+
+	if size := Size(filepath.Join(dir, "gickup", "1700000200"), ".tar.gz"); size != 30 {
+		t.Errorf("expected the size of the archive, got %d", size)
+	}
+}
@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	mu       sync.Mutex
+	current  = context.Background()
+	provider *sdktrace.TracerProvider
+)
+
+// Init exports the spans to the OTLP/HTTP endpoint of conf. Without an endpoint, spans aren't
+// recorded.
+func Init(conf types.TracingConfig) error {
+	if conf.Endpoint == "" {
+		return nil
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(conf.Endpoint),
+		otlptracehttp.WithHeaders(conf.GetHeaders()),
+	}
+
+	if conf.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(conf.GetService()),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return nil
+}
+
+// Flush exports the ended spans, it is called at the end of every run.
+func Flush() {
+	mu.Lock()
+	p := provider
+	mu.Unlock()
+
+	if p == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.ForceFlush(ctx); err != nil {
+		otel.Handle(err)
+	}
+}
+
+// Suspend makes no span the current span and returns the one which was, e.g. while a run waits
+// and another one starts spans.
+func Suspend() context.Context {
+	mu.Lock()
+	defer mu.Unlock()
+
+	suspended := current
+	current = context.Background()
+
+	return suspended
+}
+
+// Resume makes the suspended span the current span again.
+func Resume(suspended context.Context) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = suspended
+}
+
+// Span is a span which is the current span until it ends. Backups run one after another, so the
+// spans are nested by the order they are started in.
+type Span struct {
+	span   trace.Span
+	parent context.Context
+}
+
+// Start starts a span as child of the current span and makes it the current span.
+func Start(name string, attrs ...attribute.KeyValue) *Span {
+	mu.Lock()
+	defer mu.Unlock()
+
+	parent := current
+	ctx, span := otel.Tracer("github.com/cooperspencer/gickup").Start(parent, name, trace.WithAttributes(attrs...))
+	current = ctx
+
+	return &Span{span: span, parent: parent}
+}
+
+// Set adds attributes to the span.
+func (s *Span) Set(attrs ...attribute.KeyValue) {
+	s.span.SetAttributes(attrs...)
+}
+
+// End ends the span, an error marks it as failed. The parent of the span is the current span again.
+func (s *Span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = s.parent
+}
+
+// Finish ends the span, it is marked as failed unless success is set.
+func (s *Span) Finish(success bool) {
+	if !success {
+		s.span.SetStatus(codes.Error, "failed")
+	}
+
+	s.End(nil)
+}
+
+// LogHook adds the ids of the trace and the current span to the log lines.
+type LogHook struct{}
+
+// Run adds the ids, if a span is recorded.
+func (LogHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	mu.Lock()
+	sc := trace.SpanContextFromContext(current)
+	mu.Unlock()
+
+	if sc.IsValid() {
+		e.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+	}
+}
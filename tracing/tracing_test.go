@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	buf := bytes.Buffer{}
+	logger := zerolog.New(&buf).Hook(LogHook{})
+
+	run := Start("backup run")
+	clone := Start("git clone")
+	logger.Info().Msg("cloning")
+	clone.End(errors.New("repository not found"))
+	run.Finish(true)
+
+	logger.Info().Msg("done")
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("the clone isn't a child of the run")
+	}
+
+	if spans[0].Status().Code != codes.Error {
+		t.Error("the failed clone isn't marked as error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"trace_id":"`+spans[0].SpanContext().TraceID().String()) {
+		t.Errorf("the log line has no trace id: %s", lines[0])
+	}
+
+	if strings.Contains(lines[1], "trace_id") {
+		t.Errorf("the log line outside of a span has a trace id: %s", lines[1])
+	}
+}
+
+func TestExport(t *testing.T) {
+	received := make(chan string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r.URL.Path + " " + r.Header.Get("X-Token"):
+		default:
+		}
+	}))
+	defer collector.Close()
+
+	conf := types.TracingConfig{
+		Endpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure: true,
+		Headers:  map[string]string{"X-Token": "secret"},
+	}
+
+	if err := Init(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	Start("backup run").End(nil)
+	Flush()
+
+	select {
+	case got := <-received:
+		if got != "/v1/traces secret" {
+			t.Errorf("unexpected export %s", got)
+		}
+	default:
+		t.Error("no spans were exported")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cooperspencer/gickup/logger"
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/tracing"
+	"github.com/cooperspencer/gickup/types"
+	"github.com/cooperspencer/gickup/webhook"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// rediscoverEvery limits how often a source is listed again for pushes of repositories it didn't have.
+const rediscoverEvery = 5 * time.Minute
+
+// job identifies a job of a configuration, the jobs of one configuration can list different sources.
+type job struct {
+	num, index int
+}
+
+// repoCache holds the repositories discovered during the last run of every job.
+type repoCache struct {
+	mu        sync.Mutex
+	repos     map[job]map[string][]types.Repo
+	refreshed map[job]map[string]time.Time
+}
+
+var discovered = &repoCache{repos: map[job]map[string][]types.Repo{}, refreshed: map[job]map[string]time.Time{}}
+
+func (c *repoCache) set(num, index int, source string, repos []types.Repo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j := job{num, index}
+	if _, ok := c.repos[j]; !ok {
+		c.repos[j] = map[string][]types.Repo{}
+		c.refreshed[j] = map[string]time.Time{}
+	}
+
+	c.repos[j][source] = repos
+	c.refreshed[j][source] = time.Now()
+}
+
+// get returns the repositories all jobs of the configuration num discovered, each once.
+func (c *repoCache) get(num int) []types.Repo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := map[string]bool{}
+	repos := []types.Repo{}
+	for j, bysource := range c.repos {
+		if j.num != num {
+			continue
+		}
+
+		for _, list := range bysource {
+			for _, r := range list {
+				key := r.Hoster + "/" + r.Owner + "/" + r.Name
+				if !seen[key] {
+					seen[key] = true
+					repos = append(repos, r)
+				}
+			}
+		}
+	}
+
+	return repos
+}
+
+// stale checks if the source of the job wasn't listed within rediscoverEvery.
+func (c *repoCache) stale(num, index int, source string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Since(c.refreshed[job{num, index}][source]) > rediscoverEvery
+}
+
+// hostedOn checks if source of conf lists repositories of hoster, only these sources send webhooks.
+func hostedOn(conf *types.Conf, source, hoster string) bool {
+	var repos []types.GenRepo
+	defaultURL := ""
+
+	switch source {
+	case "github":
+		return len(conf.Source.Github) > 0 && strings.EqualFold(hoster, "github.com")
+	case "gitea":
+		repos, defaultURL = conf.Source.Gitea, "https://gitea.com"
+	case "gogs":
+		repos = conf.Source.Gogs
+	case "gitlab":
+		repos, defaultURL = conf.Source.Gitlab, "https://gitlab.com"
+	}
+
+	for _, repo := range repos {
+		u := repo.URL
+		if u == "" {
+			u = defaultURL
+		}
+
+		if strings.EqualFold(types.GetHost(u), hoster) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findRepos returns the repositories of conf the event was sent for. Only the sources of the hoster
+// of the event are asked again if the repository wasn't discovered before, at most every rediscoverEvery.
+func findRepos(conf *types.Conf, num int, e webhook.Event) []types.Repo {
+	match := func(repos []types.Repo) []types.Repo {
+		matched := []types.Repo{}
+		for _, r := range repos {
+			if e.Matches(r) {
+				matched = append(matched, r)
+			}
+		}
+
+		return matched
+	}
+
+	matched := match(discovered.get(num))
+	if len(matched) > 0 {
+		return matched
+	}
+
+	for _, source := range sources {
+		if !hostedOn(conf, source.name, e.Hoster) || !discovered.stale(num, conf.Job, source.name) {
+			continue
+		}
+
+		repos, ran := source.get(conf)
+		if ran {
+			discovered.set(num, conf.Job, source.name, repos)
+		}
+	}
+
+	return match(discovered.get(num))
+}
+
+// triggerBackup backs up the repository of a webhook event to all destinations of the active configurations it is part of.
+func triggerBackup(confs func() []*types.Conf) func(webhook.Event) {
+	return func(e webhook.Event) {
+		found := false
+
+		for num, conf := range confs() {
+			if backupEvent(conf, num, e) {
+				found = true
+			}
+		}
+
+		if !found {
+			logger.Background().Warn().
+				Str("stage", "webhook").
+				Str("hoster", e.Hoster).
+				Msgf("%s is not part of any configuration", types.Red(e.Key()))
+		}
+	}
+}
+
+// backupEvent backs up the repository of the event if it is part of conf. The sources are listed
+// while holding the backup lock, like during a run.
+func backupEvent(conf *types.Conf, num int, e webhook.Event) bool {
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	repos := findRepos(conf, num, e)
+	if len(repos) == 0 {
+		return false
+	}
+
+	log.Info().
+		Str("stage", "webhook").
+		Str("hoster", e.Hoster).
+		Msgf("backing up %s", types.Blue(e.Key()))
+
+	logger.NewRun()
+	span := tracing.Start("webhook backup", attribute.Int("config", num), attribute.String("repo", e.Key()))
+	backup(repos, conf, num)
+	span.End(nil)
+	logger.EndRun()
+
+	steps := plan.Take()
+	if cli.Dry {
+		writePlan(steps)
+	}
+
+	return true
+}
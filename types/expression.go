@@ -0,0 +1,121 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+)
+
+// exprEnv is what filter expressions are evaluated against, e.g.
+// private && owner == "corp" && "prod" in topics || within(pushed_at, "7d").
+type exprEnv struct {
+	Name       string    `expr:"name"`
+	Owner      string    `expr:"owner"`
+	FullPath   string    `expr:"full_path"`
+	Stars      int       `expr:"stars"`
+	Language   string    `expr:"language"`
+	Topics     []string  `expr:"topics"`
+	Visibility string    `expr:"visibility"`
+	Private    bool      `expr:"private"`
+	Archived   bool      `expr:"archived"`
+	Fork       bool      `expr:"fork"`
+	Template   bool      `expr:"template"`
+	Mirror     bool      `expr:"mirror"`
+	Empty      bool      `expr:"empty"`
+	Size       int64     `expr:"size"`
+	CreatedAt  time.Time `expr:"created_at"`
+	PushedAt   time.Time `expr:"pushed_at"`
+
+	// Within checks if the time is within the last age, e.g. within(pushed_at, "1y6M").
+	Within func(time.Time, string) bool `expr:"within"`
+	// Before checks if the time is before the date, e.g. before(created_at, "2020-01-01").
+	Before func(time.Time, string) bool `expr:"before"`
+	// After checks if the time is after the date.
+	After func(time.Time, string) bool `expr:"after"`
+	// Bytes parses sizes, e.g. size < bytes("1GB").
+	Bytes func(string) int64 `expr:"bytes"`
+}
+
+func newExprEnv(meta RepoMeta) exprEnv {
+	return exprEnv{
+		Name:       meta.Name,
+		Owner:      meta.Owner,
+		FullPath:   meta.FullPath,
+		Stars:      meta.Stars,
+		Language:   meta.Language,
+		Topics:     meta.Topics,
+		Visibility: meta.Visibility,
+		Private:    meta.Visibility == VisibilityPrivate,
+		Archived:   meta.Archived,
+		Fork:       meta.Fork,
+		Template:   meta.Template,
+		Mirror:     meta.Mirror,
+		Empty:      meta.Empty,
+		Size:       meta.Size,
+		CreatedAt:  meta.Created,
+		PushedAt:   meta.LastActivity,
+		Within: func(t time.Time, age string) bool {
+			duration, err := parseAge(age)
+			return err == nil && !t.IsZero() && time.Since(t) <= duration
+		},
+		Before: func(t time.Time, date string) bool {
+			d, err := parseDate(date)
+			return err == nil && !t.IsZero() && t.Before(d)
+		},
+		After: func(t time.Time, date string) bool {
+			d, err := parseDate(date)
+			return err == nil && !t.IsZero() && t.After(d)
+		},
+		Bytes: func(size string) int64 {
+			b, _ := ParseSize(size)
+			return b
+		},
+	}
+}
+
+var (
+	programsMu sync.Mutex
+	programs   = map[string]*vm.Program{}
+)
+
+// compileExpression compiles the filter expression, compiled expressions are cached.
+func compileExpression(expression string) (*vm.Program, error) {
+	programsMu.Lock()
+	defer programsMu.Unlock()
+
+	if program, ok := programs[expression]; ok {
+		return program, nil
+	}
+
+	program, err := expr.Compile(expression, expr.Env(exprEnv{}), expr.AsBool())
+	if err != nil {
+		return nil, err
+	}
+
+	programs[expression] = program
+
+	return program, nil
+}
+
+// evalExpression checks if a repository matches the filter expression.
+func evalExpression(expression string, meta RepoMeta) (bool, error) {
+	program, err := compileExpression(expression)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := expr.Run(program, newExprEnv(meta))
+	if err != nil {
+		return false, err
+	}
+
+	matched, ok := out.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v instead of true or false", out)
+	}
+
+	return matched, nil
+}
@@ -0,0 +1,309 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/rs/zerolog/log"
+)
+
+// RepoMeta is the metadata of a repository the filters are evaluated against.
+// Sources fill in what their hoster provides, zero values are unknown.
+type RepoMeta struct {
+	// Hoster is the host of the repository, e.g. github.com, skipped repositories are reported with it.
+	Hoster       string
+	Name         string
+	Owner        string
+	FullPath     string
+	Stars        int
+	Language     string
+	Topics       []string
+	Visibility   string
+	Archived     bool
+	Fork         bool
+	Template     bool
+	Mirror       bool
+	Empty        bool
+	Size         int64
+	Created      time.Time
+	LastActivity time.Time
+	// AheadOfParent counts the commits of a fork which aren't in its parent, it is only called
+	// for divergedforks. Sources which can't compare a fork with its parent leave it nil.
+	AheadOfParent func() (int, error)
+}
+
+// Visibilities of repositories.
+const (
+	VisibilityPublic   = "public"
+	VisibilityPrivate  = "private"
+	VisibilityInternal = "internal"
+)
+
+// VisibilityOf returns the visibility of a repository which is either private or public.
+func VisibilityOf(private bool) string {
+	if private {
+		return VisibilityPrivate
+	}
+
+	return VisibilityPublic
+}
+
+// dateLayouts are the layouts createdbefore and createdafter can be written in.
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+func parseDate(date string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%s is no date, use e.g. 2006-01-02", date)
+}
+
+// sizeUnits are the units sizes can be written in.
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses sizes like 500MB or 2G into bytes, the units are powers of 1024.
+func ParseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	factor := int64(1)
+
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			factor = unit.factor
+
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%s is no size, use e.g. 500MB", size)
+	}
+
+	return int64(value * float64(factor)), nil
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func anyFold(list, values []string) bool {
+	for _, value := range values {
+		if containsFold(list, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSet checks if any filter is configured.
+func (f Filter) IsSet() bool {
+	f.LastActivityDuration = 0
+
+	return !reflect.DeepEqual(f, Filter{})
+}
+
+// Match checks if a repository passes the filter and returns the reason if it doesn't.
+// Invalid sizes and dates are ignored, Conf.Validate reports them.
+func (f Filter) Match(meta RepoMeta) (bool, string) {
+	if f.ExcludeArchived && meta.Archived {
+		return false, "archived"
+	}
+
+	if f.ExcludeTemplates && meta.Template {
+		return false, "template"
+	}
+
+	if f.ExcludeMirrors && meta.Mirror {
+		return false, "mirror"
+	}
+
+	if f.ExcludeEmpty && meta.Empty {
+		return false, "empty"
+	}
+
+	if f.Visibility != "" && !strings.EqualFold(f.Visibility, meta.Visibility) {
+		return false, fmt.Sprintf("visibility is %s", meta.Visibility)
+	}
+
+	if meta.Stars < f.Stars {
+		return false, fmt.Sprintf("%d stars, less than %d", meta.Stars, f.Stars)
+	}
+
+	// repositories without a detected language are kept, hosters don't detect one for every repository
+	if len(f.Languages) > 0 && meta.Language != "" && !containsFold(f.Languages, meta.Language) {
+		return false, fmt.Sprintf("language %s isn't in languages", meta.Language)
+	}
+
+	if len(f.Topics) > 0 && !anyFold(f.Topics, meta.Topics) {
+		return false, "none of the topics"
+	}
+
+	if len(f.ExcludeTopics) > 0 && anyFold(f.ExcludeTopics, meta.Topics) {
+		return false, "excluded topic"
+	}
+
+	if f.LastActivityDuration != 0 && !meta.LastActivity.IsZero() && time.Since(meta.LastActivity) > f.LastActivityDuration {
+		return false, fmt.Sprintf("no activity since %s", meta.LastActivity.Format("2006-01-02"))
+	}
+
+	if meta.Size > 0 {
+		if max, err := ParseSize(f.MaxSize); f.MaxSize != "" && err == nil && meta.Size > max {
+			return false, fmt.Sprintf("size %s exceeds %s", FormatSize(meta.Size), f.MaxSize)
+		}
+
+		if min, err := ParseSize(f.MinSize); f.MinSize != "" && err == nil && meta.Size < min {
+			return false, fmt.Sprintf("size %s is below %s", FormatSize(meta.Size), f.MinSize)
+		}
+	}
+
+	if !meta.Created.IsZero() {
+		if before, err := parseDate(f.CreatedBefore); f.CreatedBefore != "" && err == nil && !meta.Created.Before(before) {
+			return false, fmt.Sprintf("created %s", meta.Created.Format("2006-01-02"))
+		}
+
+		if after, err := parseDate(f.CreatedAfter); f.CreatedAfter != "" && err == nil && !meta.Created.After(after) {
+			return false, fmt.Sprintf("created %s", meta.Created.Format("2006-01-02"))
+		}
+	}
+
+	if f.Expression != "" {
+		matched, err := evalExpression(f.Expression, meta)
+		if err != nil {
+			return false, fmt.Sprintf("expression failed: %s", err.Error())
+		}
+
+		if !matched {
+			return false, "expression is false"
+		}
+	}
+
+	return true, ""
+}
+
+// Select checks if a repository of the source is backed up, based on the include and exclude lists, the forks
+// setting and the filter. The reason is returned for repositories which aren't.
+func (grepo GenRepo) Select(sel Selector, meta RepoMeta) (bool, string) {
+	switch strings.ToLower(grepo.Forks) {
+	case ForksExclude:
+		if meta.Fork {
+			return false, "fork"
+		}
+	case ForksOnly:
+		if !meta.Fork {
+			return false, "no fork"
+		}
+	}
+
+	if ok, reason := grepo.Filter.Match(meta); !ok {
+		return false, reason
+	}
+
+	if ok, reason := sel.Explain(meta.Name, meta.Owner, meta.FullPath); !ok {
+		return false, reason
+	}
+
+	// comparing a fork with its parent costs a request, so it is done last
+	if grepo.DivergedForks && meta.Fork && meta.AheadOfParent != nil {
+		ahead, err := meta.AheadOfParent()
+		if err != nil {
+			log.Warn().
+				Str("owner", meta.Owner).
+				Str("repo", meta.Name).
+				Msgf("can't compare fork with its parent, keeping it: %s", err.Error())
+		} else if ahead == 0 {
+			return false, "fork without own commits"
+		}
+	}
+
+	return true, ""
+}
+
+// Accept is Select, logging the reason why a repository is skipped.
+func (grepo GenRepo) Accept(stage string, sel Selector, meta RepoMeta) bool {
+	ok, reason := grepo.Select(sel, meta)
+	if !ok {
+		Skip(stage, meta, reason)
+	}
+
+	return ok
+}
+
+// Skip logs the reason why a repository is skipped and adds it to the plan of a dry-run.
+func Skip(stage string, meta RepoMeta, reason string) {
+	repo := meta.Owner + "/" + meta.Name
+
+	log.Debug().
+		Str("stage", stage).
+		Str("owner", meta.Owner).
+		Str("repo", meta.Name).
+		Msgf("skipping repository, %s", reason)
+
+	source := meta.Hoster
+	if source == "" {
+		source = stage
+	}
+
+	plan.Add(plan.Step{Source: source, Repo: repo, Action: plan.Skip, Reason: reason})
+}
+
+// FormatSize formats bytes human readable, e.g. 1.5 GiB.
+func FormatSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+
+	return strings.Replace(fmt.Sprintf("%.1f %s", value, units[i]), ".0 ", " ", 1)
+}
+
+// NeedsLanguage checks if the language of a repository is needed to evaluate the filter,
+// sources which need an extra request to get it only do so if it is.
+func (f Filter) NeedsLanguage() bool {
+	return len(f.Languages) > 0 || strings.Contains(f.Expression, "language")
+}
+
+// NeedsEmpty checks if it is needed to know whether a repository is empty to evaluate the filter.
+func (f Filter) NeedsEmpty() bool {
+	return f.ExcludeEmpty || strings.Contains(f.Expression, "empty")
+}
+
+// NeedsTopics checks if the topics of a repository are needed to evaluate the filter.
+func (f Filter) NeedsTopics() bool {
+	return len(f.Topics) > 0 || len(f.ExcludeTopics) > 0 || strings.Contains(f.Expression, "topics")
+}
+
+// NeedsSize checks if the size of a repository is needed to evaluate the filter.
+func (f Filter) NeedsSize() bool {
+	return f.MinSize != "" || f.MaxSize != "" || strings.Contains(f.Expression, "size")
+}
@@ -0,0 +1,157 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cooperspencer/gickup/plan"
+)
+
+func TestParseSize(t *testing.T) {
+	for size, expected := range map[string]int64{
+		"100":   100,
+		"1KB":   1024,
+		"1.5M":  1536 * 1024,
+		"2 GB":  2 << 30,
+		"500mb": 500 << 20,
+	} {
+		parsed, err := ParseSize(size)
+		if err != nil {
+			t.Errorf("%s: %s", size, err.Error())
+		} else if parsed != expected {
+			t.Errorf("%s: expected %d, got %d", size, expected, parsed)
+		}
+	}
+
+	if _, err := ParseSize("big"); err == nil {
+		t.Error("expected an error for big")
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Now()
+	meta := RepoMeta{
+		Name:         "gickup",
+		Owner:        "cooperspencer",
+		Stars:        10,
+		Language:     "Go",
+		Topics:       []string{"backup", "git"},
+		Visibility:   VisibilityPublic,
+		Size:         5 << 20,
+		Created:      time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		LastActivity: now.Add(-time.Hour),
+	}
+
+	for _, tc := range []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{}, true},
+		{Filter{Stars: 10, Languages: []string{"go"}}, true},
+		{Filter{Stars: 11}, false},
+		{Filter{Languages: []string{"rust"}}, false},
+		{Filter{Topics: []string{"prod", "backup"}}, true},
+		{Filter{Topics: []string{"prod"}}, false},
+		{Filter{ExcludeTopics: []string{"git"}}, false},
+		{Filter{Visibility: "private"}, false},
+		{Filter{MaxSize: "1MB"}, false},
+		{Filter{MinSize: "1MB", MaxSize: "10MB"}, true},
+		{Filter{CreatedAfter: "2021-01-01", CreatedBefore: "2022-01-01"}, true},
+		{Filter{CreatedAfter: "2022-01-01"}, false},
+		{Filter{LastActivityDuration: 30 * time.Minute}, false},
+		{Filter{LastActivityDuration: 2 * time.Hour, ExcludeArchived: true}, true},
+	} {
+		ok, reason := tc.filter.Match(meta)
+		if ok != tc.expected {
+			t.Errorf("%+v: expected %t, got %t (%s)", tc.filter, tc.expected, ok, reason)
+		}
+
+		if !ok && reason == "" {
+			t.Errorf("%+v: no reason given", tc.filter)
+		}
+	}
+
+	if ok, reason := (Filter{Languages: []string{"rust"}}).Match(RepoMeta{}); !ok {
+		t.Errorf("repository without a detected language was skipped: %s", reason)
+	}
+}
+
+func TestFilterExpression(t *testing.T) {
+	meta := RepoMeta{
+		Name:         "api",
+		Owner:        "corp",
+		Topics:       []string{"prod"},
+		Visibility:   VisibilityPrivate,
+		Stars:        3,
+		LastActivity: time.Now().Add(-30 * 24 * time.Hour),
+	}
+
+	for expression, expected := range map[string]bool{
+		`private && owner == "corp" && "prod" in topics`:            true,
+		`private && "staging" in topics || within(pushed_at, "7d")`: false,
+		`within(pushed_at, "1M2d") && stars >= 3`:                   true,
+		`name matches "^api" && size < bytes("1GB")`:                true,
+	} {
+		ok, reason := Filter{Expression: expression}.Match(meta)
+		if ok != expected {
+			t.Errorf("%s: expected %t, got %t (%s)", expression, expected, ok, reason)
+		}
+	}
+
+	if errs := (Conf{Source: Source{Github: []GenRepo{{Filter: Filter{Expression: "stars >"}}}}}).Validate(); len(errs) != 1 {
+		t.Errorf("expected an error for an invalid expression, got %v", errs)
+	}
+}
+
+func TestSelectForks(t *testing.T) {
+	sel := GenRepo{}.Selector()
+	repo := RepoMeta{Name: "gickup", Owner: "cooperspencer"}
+	fork := RepoMeta{Name: "gickup", Owner: "someone", Fork: true}
+	ahead := func(n int) func() (int, error) {
+		return func() (int, error) { return n, nil }
+	}
+
+	for _, tc := range []struct {
+		grepo    GenRepo
+		meta     RepoMeta
+		expected bool
+	}{
+		{GenRepo{}, fork, true},
+		{GenRepo{Forks: ForksInclude}, repo, true},
+		{GenRepo{Forks: ForksExclude}, fork, false},
+		{GenRepo{Forks: ForksExclude}, repo, true},
+		{GenRepo{Forks: ForksOnly}, repo, false},
+		{GenRepo{Forks: ForksOnly}, fork, true},
+		{GenRepo{DivergedForks: true}, repo, true},
+		{GenRepo{DivergedForks: true}, fork, true},
+	} {
+		if ok, reason := tc.grepo.Select(sel, tc.meta); ok != tc.expected {
+			t.Errorf("%+v, fork %t: expected %t, got %t (%s)", tc.grepo, tc.meta.Fork, tc.expected, ok, reason)
+		}
+	}
+
+	fork.AheadOfParent = ahead(0)
+	if ok, _ := (GenRepo{DivergedForks: true}).Select(sel, fork); ok {
+		t.Error("fork without own commits was selected")
+	}
+
+	fork.AheadOfParent = ahead(2)
+	if ok, _ := (GenRepo{DivergedForks: true}).Select(sel, fork); !ok {
+		t.Error("diverged fork wasn't selected")
+	}
+
+	if errs := (Conf{Source: Source{Github: []GenRepo{{Forks: "some"}}}}).Validate(); len(errs) != 1 {
+		t.Errorf("expected an error for an unknown forks value, got %v", errs)
+	}
+}
+
+func TestSkipRecordsHoster(t *testing.T) {
+	plan.Enable()
+	Skip("gitea", RepoMeta{Hoster: "git.example.com", Name: "gickup", Owner: "me"}, "archived")
+	Skip("github", RepoMeta{Name: "old", Owner: "me"}, "archived")
+
+	steps := plan.Take()
+	if len(steps) != 2 || steps[0].Source != "git.example.com" || steps[1].Source != "github" {
+		t.Errorf("expected the host of the repository or the stage, got %v", steps)
+	}
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaID is the id of the JSON schema of the configuration.
+const SchemaID = "https://raw.githubusercontent.com/cooperspencer/gickup/main/conf.schema.json"
+
+// placeholder matches the strings which are replaced when the configuration is read, e.g. ${ENV_VARIABLE}.
+var placeholder = map[string]interface{}{"type": "string", "pattern": `\$\{[^}]*\}`}
+
+// Schema returns the JSON schema of the configuration, generated from the yaml tags of Conf.
+func Schema() ([]byte, error) {
+	schema := schemaOf(reflect.TypeOf(Conf{}))
+	// a single file can be included without a list
+	schema["properties"].(map[string]interface{})["include"] = map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			schemaOf(reflect.TypeOf([]string{})),
+		},
+	}
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = SchemaID
+	schema["title"] = "gickup configuration"
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" || field.PkgPath != "" {
+				continue
+			}
+
+			properties[name] = schemaOf(field.Type)
+		}
+
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaOf(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem()),
+		}
+	case reflect.Bool:
+		return orPlaceholder("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return orPlaceholder("integer")
+	case reflect.Float32, reflect.Float64:
+		return orPlaceholder("number")
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// orPlaceholder allows a placeholder instead of a value of the type, it is replaced before the value is decoded.
+func orPlaceholder(kind string) map[string]interface{} {
+	return map[string]interface{}{
+		"oneOf": []interface{}{map[string]interface{}{"type": kind}, placeholder},
+	}
+}
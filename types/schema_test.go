@@ -0,0 +1,50 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaAllowsPlaceholdersAndSingleInclude(t *testing.T) {
+	t.Parallel()
+
+	data, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := struct {
+		Properties struct {
+			Include struct {
+				OneOf []map[string]interface{} `json:"oneOf"`
+			} `json:"include"`
+			Destination struct {
+				Properties struct {
+					Local struct {
+						Items struct {
+							Properties map[string]struct {
+								OneOf []map[string]interface{} `json:"oneOf"`
+							} `json:"properties"`
+						} `json:"items"`
+					} `json:"local"`
+				} `json:"properties"`
+			} `json:"destination"`
+		} `json:"properties"`
+	}{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	include := schema.Properties.Include.OneOf
+	if len(include) != 2 || include[0]["type"] != "string" || include[1]["type"] != "array" {
+		t.Errorf("include isn't a file or a list of files: %v", include)
+	}
+
+	local := schema.Properties.Destination.Properties.Local.Items.Properties
+	for name, kind := range map[string]string{"bare": "boolean", "keep": "integer"} {
+		oneOf := local[name].OneOf
+		if len(oneOf) != 2 || oneOf[0]["type"] != kind || oneOf[1]["type"] != "string" {
+			t.Errorf("%s doesn't allow a placeholder: %v", name, oneOf)
+		}
+	}
+}
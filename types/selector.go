@@ -0,0 +1,179 @@
+package types
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks a pattern as regular expression, e.g. re:^infra-.
+const regexPrefix = "re:"
+
+// Patterns matches names against exact names, glob patterns like infra-* and regular expressions prefixed with re:.
+type Patterns struct {
+	exact   map[string]bool
+	globs   []string
+	regexps []*regexp.Regexp
+}
+
+// GetPatterns compiles the patterns of list, invalid regular expressions never match, Conf.Validate reports them.
+func GetPatterns(list []string) Patterns {
+	p := Patterns{exact: map[string]bool{}}
+	for _, pattern := range list {
+		switch {
+		case strings.HasPrefix(pattern, regexPrefix):
+			rx, err := regexp.Compile(strings.TrimPrefix(pattern, regexPrefix))
+			if err == nil {
+				p.regexps = append(p.regexps, rx)
+			}
+		case strings.ContainsAny(pattern, "*?["):
+			p.globs = append(p.globs, pattern)
+		default:
+			p.exact[pattern] = true
+		}
+	}
+
+	return p
+}
+
+// validatePattern checks if pattern is a valid glob pattern or regular expression.
+func validatePattern(pattern string) error {
+	if strings.HasPrefix(pattern, regexPrefix) {
+		_, err := regexp.Compile(strings.TrimPrefix(pattern, regexPrefix))
+
+		return err
+	}
+
+	_, err := path.Match(pattern, "")
+
+	return err
+}
+
+// Len returns the number of patterns.
+func (p Patterns) Len() int {
+	return len(p.exact) + len(p.globs) + len(p.regexps)
+}
+
+// Match checks if one of the candidates matches one of the patterns.
+func (p Patterns) Match(candidates ...string) bool {
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+
+		if p.exact[candidate] {
+			return true
+		}
+
+		for _, glob := range p.globs {
+			if ok, _ := path.Match(glob, candidate); ok {
+				return true
+			}
+		}
+
+		for _, rx := range p.regexps {
+			if rx.MatchString(candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Selector decides which repositories of a source are backed up, based on include, exclude, includeorgs and excludeorgs.
+type Selector struct {
+	Include     Patterns
+	Exclude     Patterns
+	IncludeOrgs Patterns
+	ExcludeOrgs Patterns
+}
+
+// Selector returns the Selector of the GenRepo.
+func (grepo GenRepo) Selector() Selector {
+	return Selector{
+		Include:     GetPatterns(grepo.Include),
+		Exclude:     GetPatterns(grepo.Exclude),
+		IncludeOrgs: GetPatterns(grepo.IncludeOrgs),
+		ExcludeOrgs: GetPatterns(grepo.ExcludeOrgs),
+	}
+}
+
+// repoCandidates returns the names a repository is matched with: its name, owner/name and its full path.
+func repoCandidates(name, owner, fullpath string) []string {
+	candidates := []string{name}
+	if owner != "" {
+		candidates = append(candidates, owner+"/"+name)
+	}
+
+	return append(candidates, fullpath)
+}
+
+// orgCandidates returns org and its parents, e.g. group/subgroup and group for nested GitLab groups.
+func orgCandidates(org string) []string {
+	candidates := []string{}
+	for org != "" && org != "." {
+		candidates = append(candidates, org)
+		org = path.Dir(org)
+	}
+
+	return candidates
+}
+
+// Included checks if the repository is explicitly included.
+func (s Selector) Included(name, owner, fullpath string) bool {
+	return s.Include.Match(repoCandidates(name, owner, fullpath)...)
+}
+
+// Selected checks if the repository name of owner is backed up, fullpath is the full path of the repository
+// if it differs from owner/name. Included repositories are always selected, excluded repositories and
+// repositories of excluded organizations never are. If include is set, only included repositories are selected.
+func (s Selector) Selected(name, owner, fullpath string) bool {
+	selected, _ := s.Explain(name, owner, fullpath)
+
+	return selected
+}
+
+// Explain is Selected, returning the reason if the repository isn't selected.
+func (s Selector) Explain(name, owner, fullpath string) (bool, string) {
+	if s.Included(name, owner, fullpath) {
+		return true, ""
+	}
+
+	if s.Exclude.Match(repoCandidates(name, owner, fullpath)...) {
+		return false, "excluded"
+	}
+
+	if s.ExcludeOrgs.Match(orgCandidates(owner)...) {
+		return false, "organization excluded"
+	}
+
+	if s.Include.Len() > 0 {
+		return false, "not included"
+	}
+
+	return true, ""
+}
+
+// Reason explains why a selected repository is backed up.
+func (s Selector) Reason(name, owner, fullpath string) string {
+	switch {
+	case s.Included(name, owner, fullpath):
+		return "included"
+	case s.IncludeOrgs.Len() > 0 && s.IncludeOrgs.Match(orgCandidates(owner)...):
+		return "organization included"
+	case s.Exclude.Len() > 0 || s.ExcludeOrgs.Len() > 0:
+		return "not excluded"
+	default:
+		return "all repositories"
+	}
+}
+
+// OrgSelected checks if the repositories of the organization org are backed up.
+func (s Selector) OrgSelected(org string) bool {
+	if s.ExcludeOrgs.Match(orgCandidates(org)...) {
+		return false
+	}
+
+	return s.IncludeOrgs.Len() == 0 || s.IncludeOrgs.Match(orgCandidates(org)...)
+}
@@ -0,0 +1,98 @@
+package types
+
+import "testing"
+
+func TestPatternsMatch(t *testing.T) {
+	p := GetPatterns([]string{"gickup", "infra-*", "re:^tmp-[0-9]+$", "corp/*-archive"})
+
+	for candidate, expected := range map[string]bool{
+		"gickup":             true,
+		"gickup2":            false,
+		"infra-terraform":    true,
+		"my-infra-repo":      false,
+		"tmp-42":             true,
+		"tmp-x":              false,
+		"corp/2019-archive":  true,
+		"other/2019-archive": false,
+	} {
+		if p.Match(candidate) != expected {
+			t.Errorf("%s: expected %t", candidate, expected)
+		}
+	}
+}
+
+func TestSelectorSelected(t *testing.T) {
+	sel := GenRepo{
+		Exclude:     []string{"*-archive", "cooperspencer/dotfiles"},
+		ExcludeOrgs: []string{"corp/legacy"},
+	}.Selector()
+
+	for _, tc := range []struct {
+		name, owner, fullpath string
+		expected              bool
+	}{
+		{"gickup", "cooperspencer", "", true},
+		{"gickup-archive", "cooperspencer", "", false},
+		{"dotfiles", "cooperspencer", "", false},
+		{"dotfiles", "someone", "", true},
+		{"app", "corp/legacy", "corp/legacy/app", false},
+		{"app", "corp/legacy/sub", "corp/legacy/sub/app", false},
+		{"app", "corp/new", "corp/new/app", true},
+	} {
+		if sel.Selected(tc.name, tc.owner, tc.fullpath) != tc.expected {
+			t.Errorf("%s/%s: expected %t", tc.owner, tc.name, tc.expected)
+		}
+	}
+
+	sel = GenRepo{
+		Include:     []string{"re:^infra-", "corp/legacy/app"},
+		ExcludeOrgs: []string{"corp/legacy"},
+	}.Selector()
+
+	if !sel.Selected("infra-dns", "corp", "") {
+		t.Error("included repository isn't selected")
+	}
+
+	if !sel.Selected("app", "corp/legacy", "corp/legacy/app") {
+		t.Error("included repository of an excluded organization isn't selected")
+	}
+
+	if sel.Selected("gickup", "cooperspencer", "") {
+		t.Error("repository which isn't included is selected")
+	}
+}
+
+func TestSelectorOrgSelected(t *testing.T) {
+	sel := GenRepo{IncludeOrgs: []string{"corp"}, ExcludeOrgs: []string{"corp/legacy"}}.Selector()
+
+	for org, expected := range map[string]bool{
+		"corp":            true,
+		"corp/new":        true,
+		"corp/legacy":     false,
+		"corp/legacy/sub": false,
+		"other":           false,
+	} {
+		if sel.OrgSelected(org) != expected {
+			t.Errorf("%s: expected %t", org, expected)
+		}
+	}
+}
+
+func TestSelectorReason(t *testing.T) {
+	sel := GenRepo{Include: []string{"gickup"}, IncludeOrgs: []string{"corp"}}.Selector()
+
+	for _, tc := range []struct {
+		name, owner, expected string
+	}{
+		{"gickup", "cooperspencer", "included"},
+		{"app", "corp/sub", "organization included"},
+	} {
+		if reason := sel.Reason(tc.name, tc.owner, ""); reason != tc.expected {
+			t.Errorf("%s/%s: expected %s, got %s", tc.owner, tc.name, tc.expected, reason)
+		}
+	}
+
+	if reason := (GenRepo{}).Selector().Reason("app", "corp", ""); reason != "all repositories" {
+		t.Errorf("expected all repositories, got %s", reason)
+	}
+}
@@ -1,14 +1,22 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
+	"math/rand"
 	"os"
 	"path"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/cooperspencer/gickup/plan"
+	"github.com/cooperspencer/gickup/secrets"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing/transport"
@@ -37,6 +45,20 @@ func (dest Destination) Count() int {
 		len(dest.Gitlab)
 }
 
+// Describe returns a short description of every configured destination.
+func (dest Destination) Describe() []string {
+	list := []string{}
+	for _, d := range dest.Local {
+		list = append(list, fmt.Sprintf("local %s", d.Describe()))
+	}
+	list = append(list, describe("github", dest.Github)...)
+	list = append(list, describe("gitea", dest.Gitea)...)
+	list = append(list, describe("gogs", dest.Gogs)...)
+	list = append(list, describe("gitlab", dest.Gitlab)...)
+
+	return list
+}
+
 // Local TODO.
 type Local struct {
 	Bare        bool   `yaml:"bare"`
@@ -44,26 +66,317 @@ type Local struct {
 	Structured  bool   `yaml:"structured"`
 	Compression string `yaml:"compression"`
 	Keep        int    `yaml:"keep"`
+	Cron        string `yaml:"cron"`
+	// MinFree is the space which is kept free, repositories which don't fit are not cloned.
+	MinFree string `yaml:"minfree"`
+}
+
+// Describe returns the path of the Local destination.
+func (l Local) Describe() string {
+	return l.Path
 }
 
 // Conf TODO.
 type Conf struct {
-	Source      Source      `yaml:"source"`
-	Destination Destination `yaml:"destination"`
-	Cron        string      `yaml:"cron"`
-	Log         Logging     `yaml:"log"`
-	Metrics     Metrics     `yaml:"metrics"`
+	Source      Source             `yaml:"source"`
+	Destination Destination        `yaml:"destination"`
+	Cron        string             `yaml:"cron"`
+	Log         Logging            `yaml:"log"`
+	Metrics     Metrics            `yaml:"metrics"`
+	Webhook     WebhookConfig      `yaml:"webhook"`
+	Timezone    string             `yaml:"timezone"`
+	Jitter      string             `yaml:"jitter"`
+	Window      Window             `yaml:"window"`
+	Include     []string           `yaml:"include"`
+	Defaults    map[string]GenRepo `yaml:"defaults"`
+	// Order is largest or smallest, the repositories of a source are backed up in the order of their size.
+	Order  string       `yaml:"order"`
+	Report ReportConfig `yaml:"report"`
+	// Job is the index of the job Split made this configuration, runs of the jobs of one configuration are kept apart with it.
+	Job int `yaml:"-"`
 }
 
-// PrometheusConfig TODO.
-type PrometheusConfig struct {
+// ReportConfig are the files the report of the backup runs is written to.
+type ReportConfig struct {
+	JSON  string `yaml:"json"`
+	JUnit string `yaml:"junit"`
+}
+
+// IsSet checks if a report is written.
+func (r ReportConfig) IsSet() bool {
+	return r.JSON != "" || r.JUnit != ""
+}
+
+// Orders of the repositories of a backup.
+const (
+	OrderLargest  = "largest"
+	OrderSmallest = "smallest"
+)
+
+// Window is the time of the day backups are allowed to run in, e.g. from 22:00 to 06:00.
+type Window struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// IsSet checks if the window is configured.
+func (w Window) IsSet() bool {
+	return w.Start != "" && w.End != ""
+}
+
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate checks the start and end of the window.
+func (w Window) Validate() error {
+	if _, err := parseClock(w.Start); err != nil {
+		return err
+	}
+
+	_, err := parseClock(w.End)
+
+	return err
+}
+
+// Contains checks if t is inside the window, windows can span midnight.
+func (w Window) Contains(t time.Time) bool {
+	if !w.IsSet() {
+		return true
+	}
+
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return true
+	}
+
+	end, err := parseClock(w.End)
+	if err != nil {
+		return true
+	}
+
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return now >= start && now < end
+	default:
+		return now >= start || now < end
+	}
+}
+
+// Next returns when the window opens next after t, or t if t is inside the window.
+func (w Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	start, _ := parseClock(w.Start)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	next := midnight.Add(start)
+	if !next.After(t) {
+		next = midnight.AddDate(0, 0, 1).Add(start)
+	}
+
+	return next
+}
+
+// Location returns the timezone of the configuration, defaults to the local timezone.
+func (conf Conf) Location() *time.Location {
+	if conf.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		log.Error().Str("timezone", conf.Timezone).Msg(err.Error())
+		return time.Local
+	}
+
+	return loc
+}
+
+// CronSpec returns the cron including the timezone of the configuration.
+func (conf Conf) CronSpec() string {
+	if conf.Timezone == "" || strings.HasPrefix(conf.Cron, "TZ=") || strings.HasPrefix(conf.Cron, "CRON_TZ=") {
+		return conf.Cron
+	}
+
+	return fmt.Sprintf("CRON_TZ=%s %s", conf.Timezone, conf.Cron)
+}
+
+// GetJitter returns a random delay up to the configured jitter.
+func (conf Conf) GetJitter() time.Duration {
+	if conf.Jitter == "" {
+		return 0
+	}
+
+	jitter, err := time.ParseDuration(conf.Jitter)
+	if err != nil {
+		log.Error().Str("jitter", conf.Jitter).Msg(err.Error())
+		return 0
+	}
+
+	if jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// InWindow checks if the window of the configuration is open.
+func (conf Conf) InWindow() bool {
+	return conf.Window.Contains(time.Now().In(conf.Location()))
+}
+
+// NextWindow returns when the window of the configuration opens next, or now if it is open.
+func (conf Conf) NextWindow() time.Time {
+	return conf.Window.Next(time.Now().In(conf.Location()))
+}
+
+// WebhookConfig TODO.
+type WebhookConfig struct {
+	ListenAddr string `yaml:"listen_addr"`
+	Endpoint   string `yaml:"endpoint"`
+	Secret     string `yaml:"secret"`
+	Debounce   string `yaml:"debounce"`
+}
+
+// GetEndpoint returns the endpoint of the webhook, defaults to "/webhook".
+func (w WebhookConfig) GetEndpoint() string {
+	if w.Endpoint == "" {
+		return "/webhook"
+	}
+
+	return w.Endpoint
+}
+
+// GetSecret returns the secret, which can be the name of an environment variable.
+func (w WebhookConfig) GetSecret() string {
+	if w.Secret == "" {
+		return ""
+	}
+
+	return resolve(w.Secret)
+}
+
+// GetDebounce returns how long to wait for further pushes before backing up, defaults to 30 seconds.
+func (w WebhookConfig) GetDebounce() time.Duration {
+	if w.Debounce != "" {
+		d, err := time.ParseDuration(w.Debounce)
+		if err == nil {
+			return d
+		}
+
+		log.Warn().Str("debounce", w.Debounce).Msg(err.Error())
+	}
+
+	return 30 * time.Second
+}
+
+// DashboardConfig TODO.
+type DashboardConfig struct {
 	ListenAddr string `yaml:"listen_addr"`
 	Endpoint   string `yaml:"endpoint"`
 }
 
+// GetEndpoint returns the endpoint of the dashboard, defaults to "/".
+func (d DashboardConfig) GetEndpoint() string {
+	if d.Endpoint == "" {
+		return "/"
+	}
+
+	return d.Endpoint
+}
+
+// PrometheusConfig TODO.
+type PrometheusConfig struct {
+	ListenAddr  string            `yaml:"listen_addr"`
+	Endpoint    string            `yaml:"endpoint"`
+	Pushgateway PushgatewayConfig `yaml:"pushgateway"`
+}
+
+// PushgatewayConfig is a pushgateway the metrics are pushed to at the end of every run.
+type PushgatewayConfig struct {
+	URL      string `yaml:"url"`
+	Job      string `yaml:"job"`
+	Instance string `yaml:"instance"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+}
+
+// GetJob returns the job grouping label, defaults to gickup.
+func (p PushgatewayConfig) GetJob() string {
+	if p.Job == "" {
+		return "gickup"
+	}
+
+	return p.Job
+}
+
+// GetInstance returns the instance grouping label, defaults to the hostname.
+func (p PushgatewayConfig) GetInstance() string {
+	if p.Instance != "" {
+		return p.Instance
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "gickup"
+	}
+
+	return hostname
+}
+
+// GetPassword returns the password, which can be an environment variable or a secret reference.
+func (p PushgatewayConfig) GetPassword() string {
+	if p.Password == "" {
+		return ""
+	}
+
+	return resolve(p.Password)
+}
+
 // HeartbeatConfig TODO.
 type HeartbeatConfig struct {
-	URLs []string `yaml:"urls"`
+	URLs    []string         `yaml:"urls"`
+	Checks  []HeartbeatCheck `yaml:"checks"`
+	Timeout string           `yaml:"timeout"`
+}
+
+// HeartbeatCheck is a check of a healthchecks.io-style service. The url is pinged when the run
+// succeeded, url/start when it starts and url/fail with a log excerpt when a repository failed.
+type HeartbeatCheck struct {
+	URL   string `yaml:"url"`
+	Start bool   `yaml:"start"`
+	Fail  bool   `yaml:"fail"`
+}
+
+// GetTimeout returns the timeout of the requests of heartbeats, defaults to 10 seconds.
+func (h HeartbeatConfig) GetTimeout() time.Duration {
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err == nil {
+			return d
+		}
+
+		log.Warn().Str("timeout", h.Timeout).Msg(err.Error())
+	}
+
+	return 10 * time.Second
+}
+
+// IsSet checks if any heartbeat is configured.
+func (h HeartbeatConfig) IsSet() bool {
+	return len(h.URLs) > 0 || len(h.Checks) > 0
 }
 
 // PushConfig TODO.
@@ -72,8 +385,60 @@ type PushConfig struct {
 	Password string `yaml:"password"`
 	Token    string `yaml:"token"`
 	Url      string `yaml:"url"`
+	// Title and Message are text/template templates of the notification.
+	Title   string `yaml:"title"`
+	Message string `yaml:"message"`
+	// On is always, failure, success or change.
+	On string `yaml:"on"`
+	// Room is the room id of matrix.
+	Room string `yaml:"room"`
+	// Method, Headers and Body are the request of a webhook, Body is a text/template template. Body is
+	// the html/template template of the html part of an email.
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// From, To and TLS are the sender, recipients and encryption of an email.
+	From string   `yaml:"from"`
+	To   []string `yaml:"to"`
+	TLS  string   `yaml:"tls"`
+}
+
+// Encryptions of SMTP connections.
+const (
+	SMTPStartTLS = "starttls"
+	SMTPImplicit = "implicit"
+	SMTPNone     = "none"
+)
+
+// Notification is a rendered notification, Summary is the summary of the run it was rendered with.
+type Notification struct {
+	Title   string      `json:"title"`
+	Message string      `json:"message"`
+	Summary interface{} `json:"summary"`
+}
+
+// BodyFuncs are the functions of webhook body templates, json quotes a value.
+var BodyFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+
+		return string(data), err
+	},
+}
+
+// MailFuncs are the functions of the html templates of smtp bodies, lines splits a text into its lines.
+var MailFuncs = htmltemplate.FuncMap{
+	"lines": func(s string) []string { return strings.Split(s, "\n") },
 }
 
+// When notifications are sent.
+const (
+	NotifyAlways  = "always"
+	NotifyFailure = "failure"
+	NotifySuccess = "success"
+	NotifyChange  = "change"
+)
+
 func (p *PushConfig) ResolveToken() {
 	if p.Password != "" {
 		p.Password = resolve(p.Password)
@@ -81,9 +446,26 @@ func (p *PushConfig) ResolveToken() {
 	if p.Token != "" {
 		p.Token = resolve(p.Token)
 	}
+	if len(p.Headers) > 0 {
+		// the map is shared with the copies of the config
+		headers := map[string]string{}
+		for name, value := range p.Headers {
+			headers[name] = resolve(value)
+		}
+		p.Headers = headers
+	}
 }
 
 func resolve(value string) string {
+	if secrets.IsReference(value) {
+		secret, err := secrets.Resolve(value)
+		if err != nil {
+			log.Error().Msg(err.Error())
+		}
+
+		return secret
+	}
+
 	envtoken := os.Getenv(value)
 	if envtoken != "" {
 		return envtoken
@@ -94,8 +476,36 @@ func resolve(value string) string {
 
 // PushConfigs TODO.
 type PushConfigs struct {
-	Ntfy   []*PushConfig `yaml:"ntfy"`
-	Gotify []*PushConfig `yaml:"gotify"`
+	Ntfy    []*PushConfig `yaml:"ntfy"`
+	Gotify  []*PushConfig `yaml:"gotify"`
+	Webhook []*PushConfig `yaml:"webhook"`
+	Slack   []*PushConfig `yaml:"slack"`
+	Discord []*PushConfig `yaml:"discord"`
+	Matrix  []*PushConfig `yaml:"matrix"`
+	Teams   []*PushConfig `yaml:"teams"`
+	SMTP    []*PushConfig `yaml:"smtp"`
+}
+
+// All returns the configs of every service by its name, e.g. ntfy.
+func (p PushConfigs) All() map[string][]*PushConfig {
+	all := map[string][]*PushConfig{}
+	v := reflect.ValueOf(p)
+	for i := 0; i < v.NumField(); i++ {
+		all[v.Type().Field(i).Tag.Get("yaml")] = v.Field(i).Interface().([]*PushConfig)
+	}
+
+	return all
+}
+
+// IsSet checks if any notification is configured.
+func (p PushConfigs) IsSet() bool {
+	for _, configs := range p.All() {
+		if len(configs) > 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 // Metrics TODO.
@@ -103,19 +513,61 @@ type Metrics struct {
 	Prometheus  PrometheusConfig `yaml:"prometheus"`
 	Heartbeat   HeartbeatConfig  `yaml:"heartbeat"`
 	PushConfigs PushConfigs      `yaml:"push"`
+	Dashboard   DashboardConfig  `yaml:"dashboard"`
+	Tracing     TracingConfig    `yaml:"tracing"`
+}
+
+// TracingConfig is an OTLP/HTTP endpoint the traces of the runs are exported to.
+type TracingConfig struct {
+	Endpoint string            `yaml:"endpoint"`
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+	Service  string            `yaml:"service"`
+}
+
+// GetService returns the name of the traced service, defaults to gickup.
+func (t TracingConfig) GetService() string {
+	if t.Service == "" {
+		return "gickup"
+	}
+
+	return t.Service
+}
+
+// GetHeaders returns the headers of the exports, the values can be environment variables or secret
+// references.
+func (t TracingConfig) GetHeaders() map[string]string {
+	headers := map[string]string{}
+	for name, value := range t.Headers {
+		headers[name] = resolve(value)
+	}
+
+	return headers
 }
 
 // Logging TODO.
 type Logging struct {
 	Timeformat  string      `yaml:"timeformat"`
 	FileLogging FileLogging `yaml:"file-logging"`
+	// Format is the format of the console, console or json.
+	Format string `yaml:"format"`
+	// Level is the lowest level written to the console.
+	Level string `yaml:"level"`
 }
 
+// Formats of the console log.
+const (
+	LogConsole = "console"
+	LogJSON    = "json"
+)
+
 // FileLogging TODO.
 type FileLogging struct {
 	Dir    string `yaml:"dir"`
 	File   string `yaml:"file"`
 	MaxAge int    `yaml:"maxage"`
+	// Level is the lowest level written to the file.
+	Level string `yaml:"level"`
 }
 
 // CheckAllValuesOrNone TODO.
@@ -154,6 +606,16 @@ func (conf Conf) HasAllPrometheusConf() bool {
 	return ok
 }
 
+// HasWebhookConf TODO.
+func (conf Conf) HasWebhookConf() bool {
+	return len(conf.Webhook.ListenAddr) > 0
+}
+
+// HasDashboardConf TODO.
+func (conf Conf) HasDashboardConf() bool {
+	return len(conf.Metrics.Dashboard.ListenAddr) > 0
+}
+
 // MissingCronSpec TODO.
 func (conf Conf) MissingCronSpec() bool {
 	return conf.Cron == ""
@@ -175,7 +637,7 @@ func (conf Conf) GetNextRun() (*time.Time, error) {
 		return nil, fmt.Errorf("cron unspecified")
 	}
 
-	parsedSched, err := ParseCronSpec(conf.Cron)
+	parsedSched, err := ParseCronSpec(conf.CronSpec())
 	if err != nil {
 		return nil, err
 	}
@@ -191,11 +653,157 @@ func (conf Conf) HasValidCronSpec() bool {
 		return false
 	}
 
-	_, err := ParseCronSpec(conf.Cron)
+	_, err := ParseCronSpec(conf.CronSpec())
 
 	return err == nil
 }
 
+// Split returns a configuration per cron schedule.
+// Sources with their own cron are backed up to every destination without one on their schedule,
+// destinations with their own cron get a backup of all sources on theirs.
+func (conf *Conf) Split() []*Conf {
+	if !conf.hasOwnCron() {
+		return []*Conf{conf}
+	}
+
+	defaults := Destination{}
+	scheduled := []*Conf{}
+	schedule := func(spec string, dest Destination) {
+		c := *conf
+		c.Cron = spec
+		c.Destination = dest
+		scheduled = append(scheduled, &c)
+	}
+
+	for _, d := range conf.Destination.Local {
+		if d.Cron == "" {
+			defaults.Local = append(defaults.Local, d)
+		} else {
+			schedule(d.Cron, Destination{Local: []Local{d}})
+		}
+	}
+	for _, d := range conf.Destination.Github {
+		if d.Cron == "" {
+			defaults.Github = append(defaults.Github, d)
+		} else {
+			schedule(d.Cron, Destination{Github: []GenRepo{d}})
+		}
+	}
+	for _, d := range conf.Destination.Gitea {
+		if d.Cron == "" {
+			defaults.Gitea = append(defaults.Gitea, d)
+		} else {
+			schedule(d.Cron, Destination{Gitea: []GenRepo{d}})
+		}
+	}
+	for _, d := range conf.Destination.Gogs {
+		if d.Cron == "" {
+			defaults.Gogs = append(defaults.Gogs, d)
+		} else {
+			schedule(d.Cron, Destination{Gogs: []GenRepo{d}})
+		}
+	}
+	for _, d := range conf.Destination.Gitlab {
+		if d.Cron == "" {
+			defaults.Gitlab = append(defaults.Gitlab, d)
+		} else {
+			schedule(d.Cron, Destination{Gitlab: []GenRepo{d}})
+		}
+	}
+
+	crons := []string{}
+	bycron := map[string]*Conf{}
+	group := func(r GenRepo) *Source {
+		spec := r.Cron
+		if spec == "" {
+			spec = conf.Cron
+		}
+
+		c, ok := bycron[spec]
+		if !ok {
+			c = &Conf{}
+			*c = *conf
+			c.Cron = spec
+			c.Source = Source{}
+			c.Destination = defaults
+			bycron[spec] = c
+			crons = append(crons, spec)
+		}
+
+		return &c.Source
+	}
+
+	for _, r := range conf.Source.Gogs {
+		s := group(r)
+		s.Gogs = append(s.Gogs, r)
+	}
+	for _, r := range conf.Source.Gitlab {
+		s := group(r)
+		s.Gitlab = append(s.Gitlab, r)
+	}
+	for _, r := range conf.Source.Github {
+		s := group(r)
+		s.Github = append(s.Github, r)
+	}
+	for _, r := range conf.Source.Gitea {
+		s := group(r)
+		s.Gitea = append(s.Gitea, r)
+	}
+	for _, r := range conf.Source.BitBucket {
+		s := group(r)
+		s.BitBucket = append(s.BitBucket, r)
+	}
+	for _, r := range conf.Source.OneDev {
+		s := group(r)
+		s.OneDev = append(s.OneDev, r)
+	}
+	for _, r := range conf.Source.Sourcehut {
+		s := group(r)
+		s.Sourcehut = append(s.Sourcehut, r)
+	}
+	for _, r := range conf.Source.Any {
+		s := group(r)
+		s.Any = append(s.Any, r)
+	}
+
+	confs := []*Conf{}
+	for _, spec := range crons {
+		// without destinations of their own, these sources are only backed up by the scheduled destinations
+		if bycron[spec].Destination.Count() > 0 || len(scheduled) == 0 {
+			confs = append(confs, bycron[spec])
+		}
+	}
+
+	confs = append(confs, scheduled...)
+	for i, c := range confs {
+		c.Job = i
+	}
+
+	return confs
+}
+
+func (conf Conf) hasOwnCron() bool {
+	for _, d := range conf.Destination.Local {
+		if d.Cron != "" {
+			return true
+		}
+	}
+
+	for _, list := range [][]GenRepo{
+		conf.Destination.Github, conf.Destination.Gitea, conf.Destination.Gogs, conf.Destination.Gitlab,
+		conf.Source.Gogs, conf.Source.Gitlab, conf.Source.Github, conf.Source.Gitea,
+		conf.Source.BitBucket, conf.Source.OneDev, conf.Source.Sourcehut, conf.Source.Any,
+	} {
+		for _, r := range list {
+			if r.Cron != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Source TODO.
 type Source struct {
 	Gogs      []GenRepo `yaml:"gogs"`
@@ -220,6 +828,74 @@ func (source Source) Count() int {
 		len(source.Any)
 }
 
+// Describe returns a short description of every configured source.
+func (source Source) Describe() []string {
+	list := []string{}
+	list = append(list, describe("github", source.Github)...)
+	list = append(list, describe("gitea", source.Gitea)...)
+	list = append(list, describe("gogs", source.Gogs)...)
+	list = append(list, describe("gitlab", source.Gitlab)...)
+	list = append(list, describe("bitbucket", source.BitBucket)...)
+	list = append(list, describe("onedev", source.OneDev)...)
+	list = append(list, describe("sourcehut", source.Sourcehut)...)
+	list = append(list, describe("any", source.Any)...)
+
+	return list
+}
+
+// Only returns the sources of hoster, e.g. github. If entry is set, only the source with this index
+// or the user, username or description entry is returned.
+func (source Source) Only(hoster, entry string) (Source, error) {
+	only := Source{}
+	v := reflect.ValueOf(source)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("yaml") != hoster {
+			continue
+		}
+
+		repos := v.Field(i).Interface().([]GenRepo)
+		if entry != "" {
+			repos = findEntry(repos, entry)
+			if len(repos) == 0 {
+				return only, fmt.Errorf("%s has no source %s", hoster, entry)
+			}
+		}
+
+		reflect.ValueOf(&only).Elem().Field(i).Set(reflect.ValueOf(repos))
+
+		return only, nil
+	}
+
+	return only, fmt.Errorf("unknown source %s", hoster)
+}
+
+func findEntry(repos []GenRepo, entry string) []GenRepo {
+	if index, err := strconv.Atoi(entry); err == nil {
+		if index < 0 || index >= len(repos) {
+			return nil
+		}
+
+		return repos[index : index+1]
+	}
+
+	for _, repo := range repos {
+		if repo.User == entry || repo.Username == entry || repo.Describe() == entry {
+			return []GenRepo{repo}
+		}
+	}
+
+	return nil
+}
+
+func describe(hoster string, repos []GenRepo) []string {
+	list := []string{}
+	for _, repo := range repos {
+		list = append(list, fmt.Sprintf("%s %s", hoster, repo.Describe()))
+	}
+
+	return list
+}
+
 // GenRepo Generell Repo.
 type GenRepo struct {
 	Token       string     `yaml:"token"`
@@ -240,8 +916,21 @@ type GenRepo struct {
 	Visibility  Visibility `yaml:"visibility"`
 	Filter      Filter     `yaml:"filter"`
 	Contributed bool       `yaml:"contributed"`
+	Cron        string     `yaml:"cron"`
+	Extends     string     `yaml:"extends"`
+	// Forks is include, exclude or only.
+	Forks         string `yaml:"forks"`
+	DivergedForks bool   `yaml:"divergedforks"`
+	ParentRefs    bool   `yaml:"parentrefs"`
 }
 
+// Fork modes of sources.
+const (
+	ForksInclude = "include"
+	ForksExclude = "exclude"
+	ForksOnly    = "only"
+)
+
 // Visibility struct
 type Visibility struct {
 	Repositories  string `yaml:"repositories"`
@@ -250,28 +939,63 @@ type Visibility struct {
 
 // Filter struct
 type Filter struct {
-	LastActivityString   string `yaml:"lastactivity"`
-	LastActivityDuration time.Duration
-	Stars                int      `yaml:"stars"`
-	Languages            []string `yaml:"languages"`
-	ExcludeArchived      bool     `yaml:"excludearchived"`
+	LastActivityString   string        `yaml:"lastactivity"`
+	LastActivityDuration time.Duration `yaml:"-"`
+	Stars                int           `yaml:"stars"`
+	Languages            []string      `yaml:"languages"`
+	ExcludeArchived      bool          `yaml:"excludearchived"`
+	ExcludeTemplates     bool          `yaml:"excludetemplates"`
+	ExcludeMirrors       bool          `yaml:"excludemirrors"`
+	ExcludeEmpty         bool          `yaml:"excludeempty"`
+	Visibility           string        `yaml:"visibility"`
+	Topics               []string      `yaml:"topics"`
+	ExcludeTopics        []string      `yaml:"excludetopics"`
+	MinSize              string        `yaml:"minsize"`
+	MaxSize              string        `yaml:"maxsize"`
+	CreatedBefore        string        `yaml:"createdbefore"`
+	CreatedAfter         string        `yaml:"createdafter"`
+	Expression           string        `yaml:"expression"`
 }
 
-// GetToken TODO.
-func (grepo GenRepo) GetToken() string {
-	token, err := resolveToken(grepo.Token, grepo.TokenFile)
-	if err != nil {
-		log.Fatal().
-			Str("url", grepo.URL).
-			Str("tokenfile", grepo.TokenFile).
-			Msg(err.Error())
+// Describe returns the user and url of the GenRepo.
+func (grepo GenRepo) Describe() string {
+	user := grepo.User
+	if user == "" {
+		user = grepo.Username
 	}
 
-	return token
+	switch {
+	case user != "" && grepo.URL != "":
+		return fmt.Sprintf("%s@%s", user, grepo.URL)
+	case user != "":
+		return user
+	default:
+		return grepo.URL
+	}
+}
+
+// GetToken resolves the token of the source or destination, a failed lookup is returned so the
+// caller can skip it for this run.
+func (grepo GenRepo) GetToken() (string, error) {
+	return resolveToken(grepo.Token, grepo.TokenFile)
 }
 
 func (f *Filter) ParseDuration() error {
-	rest := strings.Trim(f.LastActivityString, " ")
+	duration, err := parseAge(f.LastActivityString)
+	if err != nil {
+		return err
+	}
+
+	if duration != 0 {
+		f.LastActivityDuration = duration
+	}
+
+	return nil
+}
+
+// parseAge parses ages like 1y2M3d or 1y12h into a duration, years, months and days are calendar based.
+func parseAge(age string) (time.Duration, error) {
+	rest := strings.Trim(age, " ")
 	date := time.Now()
 	parsed := false
 	if strings.Contains(rest, "y") {
@@ -282,7 +1006,7 @@ func (f *Filter) ParseDuration() error {
 		}
 		years, err := strconv.Atoi(yearsstring)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		date = date.AddDate(years*(-1), 0, 0)
 		parsed = true
@@ -295,7 +1019,7 @@ func (f *Filter) ParseDuration() error {
 		}
 		months, err := strconv.Atoi(monthsstring)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		date = date.AddDate(0, months*(-1), 0)
 		parsed = true
@@ -308,7 +1032,7 @@ func (f *Filter) ParseDuration() error {
 		}
 		days, err := strconv.Atoi(daysstring)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		date = date.AddDate(0, 0, days*(-1))
 		parsed = true
@@ -317,18 +1041,17 @@ func (f *Filter) ParseDuration() error {
 	if len(rest) > 0 {
 		dur, err := time.ParseDuration(rest)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		restdur = dur
 		parsed = true
 	}
 
-	if parsed {
-		f.LastActivityDuration = time.Since(date)
-		f.LastActivityDuration += restdur
+	if !parsed {
+		return 0, nil
 	}
 
-	return nil
+	return time.Since(date) + restdur, nil
 }
 
 func resolveToken(tokenString string, tokenFile string) (string, error) {
@@ -336,6 +1059,10 @@ func resolveToken(tokenString string, tokenFile string) (string, error) {
 		return "", nil
 	}
 	if tokenString != "" {
+		if secrets.IsReference(tokenString) {
+			return secrets.Resolve(tokenString)
+		}
+
 		envstring := os.Getenv(tokenString)
 		if envstring != "" {
 			return envstring, nil
@@ -374,6 +1101,41 @@ type Repo struct {
 	Hoster        string
 	Description   string
 	Private       bool
+	// ParentURL and ParentSSHURL are the clone urls of the parent if the repository is a fork,
+	// they are only set for parentrefs.
+	ParentURL    string
+	ParentSSHURL string
+	// Size is the size of the repository in bytes, 0 if the hoster doesn't tell.
+	Size int64
+}
+
+// Step returns a step of the plan of a dry-run for the backup of the repository to destination.
+func (r Repo) Step(destination, action, reason string) plan.Step {
+	return plan.Step{
+		Source:      r.Hoster,
+		Repo:        r.Owner + "/" + r.Name,
+		Destination: destination,
+		Action:      action,
+		Reason:      reason,
+	}
+}
+
+// SortRepos sorts repos largest or smallest first, repositories of unknown size come last.
+func SortRepos(repos []Repo, order string) {
+	switch strings.ToLower(order) {
+	case OrderLargest:
+		sort.SliceStable(repos, func(i, j int) bool {
+			return repos[i].Size > repos[j].Size
+		})
+	case OrderSmallest:
+		sort.SliceStable(repos, func(i, j int) bool {
+			if repos[j].Size == 0 {
+				return repos[i].Size != 0
+			}
+
+			return repos[i].Size != 0 && repos[i].Size < repos[j].Size
+		})
+	}
 }
 
 // Site TODO.
@@ -1,6 +1,9 @@
 package types
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestConfCronMissing(t *testing.T) {
 	t.Parallel()
@@ -31,3 +34,171 @@ func TestParseInvalidCron(t *testing.T) {
 		t.Error("Invalid cron spec parsed validly")
 	}
 }
+
+func TestSplitBySourceCron(t *testing.T) {
+	t.Parallel()
+
+	conf := Conf{
+		Cron: "0 0 * * *",
+		Source: Source{
+			Github: []GenRepo{{User: "critical", Cron: "0 * * * *"}, {User: "other"}},
+			Gitea:  []GenRepo{{User: "nightly"}},
+		},
+		Destination: Destination{
+			Local: []Local{{Path: "/backup"}},
+			Gitea: []GenRepo{{URL: "https://gitea.example.com", Cron: "0 12 * * *"}},
+		},
+	}
+
+	confs := conf.Split()
+	if len(confs) != 3 {
+		t.Fatalf("expected 3 configurations, got %d", len(confs))
+	}
+
+	if confs[0].Cron != "0 * * * *" || len(confs[0].Source.Github) != 1 || confs[0].Destination.Count() != 1 {
+		t.Error("source with its own cron isn't scheduled on its own")
+	}
+
+	if confs[1].Cron != "0 0 * * *" || confs[1].Source.Count() != 2 || len(confs[1].Destination.Local) != 1 {
+		t.Error("sources without cron aren't scheduled on the configuration's cron")
+	}
+
+	if confs[2].Cron != "0 12 * * *" || confs[2].Source.Count() != 3 || len(confs[2].Destination.Gitea) != 1 {
+		t.Error("destination with its own cron doesn't get all sources")
+	}
+
+	for i, c := range confs {
+		if c.Job != i {
+			t.Errorf("expected job %d, got %d", i, c.Job)
+		}
+	}
+}
+
+func TestSplitWithoutOwnCron(t *testing.T) {
+	t.Parallel()
+
+	conf := &Conf{Cron: "0 0 * * *", Source: Source{Github: []GenRepo{{User: "foo"}}}}
+
+	if confs := conf.Split(); len(confs) != 1 || confs[0] != conf {
+		t.Error("configuration without own crons was split")
+	}
+}
+
+func TestCronSpecTimezone(t *testing.T) {
+	t.Parallel()
+
+	conf := Conf{Cron: "0 22 * * *", Timezone: "Europe/Berlin"}
+
+	if conf.CronSpec() != "CRON_TZ=Europe/Berlin 0 22 * * *" {
+		t.Errorf("unexpected cron spec %s", conf.CronSpec())
+	}
+
+	if !conf.HasValidCronSpec() {
+		t.Error("cron spec with timezone parsed invalidly")
+	}
+
+	conf.Timezone = "Nowhere/Atlantis"
+	if conf.HasValidCronSpec() {
+		t.Error("cron spec with invalid timezone parsed validly")
+	}
+}
+
+func TestWindowOverMidnight(t *testing.T) {
+	t.Parallel()
+
+	w := Window{Start: "22:00", End: "06:00"}
+	day := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if !w.Contains(day.Add(23 * time.Hour)) {
+		t.Error("23:00 is not inside 22:00-06:00")
+	}
+
+	if !w.Contains(day.Add(5 * time.Hour)) {
+		t.Error("05:00 is not inside 22:00-06:00")
+	}
+
+	if w.Contains(day.Add(12 * time.Hour)) {
+		t.Error("12:00 is inside 22:00-06:00")
+	}
+
+	if next := w.Next(day.Add(12 * time.Hour)); !next.Equal(day.Add(22 * time.Hour)) {
+		t.Errorf("window opens at %s instead of 22:00", next)
+	}
+}
+
+func TestWindowNextDay(t *testing.T) {
+	t.Parallel()
+
+	w := Window{Start: "01:00", End: "03:00"}
+	day := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if next := w.Next(day.Add(4 * time.Hour)); !next.Equal(day.Add(25 * time.Hour)) {
+		t.Errorf("window opens at %s instead of 01:00 the next day", next)
+	}
+}
+
+func TestSortRepos(t *testing.T) {
+	t.Parallel()
+
+	names := func(repos []Repo) string {
+		list := ""
+		for _, r := range repos {
+			list += r.Name
+		}
+
+		return list
+	}
+
+	repos := []Repo{{Name: "a", Size: 2}, {Name: "b"}, {Name: "c", Size: 30}, {Name: "d", Size: 1}}
+
+	SortRepos(repos, OrderLargest)
+	if names(repos) != "cadb" {
+		t.Errorf("largest first: got %s", names(repos))
+	}
+
+	SortRepos(repos, OrderSmallest)
+	if names(repos) != "dacb" {
+		t.Errorf("smallest first: got %s", names(repos))
+	}
+}
+
+func TestSourceOnly(t *testing.T) {
+	t.Parallel()
+
+	source := Source{
+		Github: []GenRepo{{User: "first"}, {User: "second"}},
+		Gitea:  []GenRepo{{URL: "https://gitea.com"}},
+	}
+
+	only, err := source.Only("github", "")
+	if err != nil || len(only.Github) != 2 || len(only.Gitea) != 0 {
+		t.Errorf("unexpected sources %+v: %v", only, err)
+	}
+
+	for _, entry := range []string{"1", "second"} {
+		only, err := source.Only("github", entry)
+		if err != nil || len(only.Github) != 1 || only.Github[0].User != "second" {
+			t.Errorf("%s: unexpected sources %+v: %v", entry, only, err)
+		}
+	}
+
+	for _, entry := range []string{"2", "third"} {
+		if _, err := source.Only("github", entry); err == nil {
+			t.Errorf("%s: expected an error", entry)
+		}
+	}
+
+	if _, err := source.Only("svn", ""); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+}
+
+func TestGetTokenReturnsErrors(t *testing.T) {
+	if _, err := (GenRepo{TokenFile: "/does/not/exist"}).GetToken(); err == nil {
+		t.Error("expected an error for a missing tokenfile")
+	}
+
+	if token, err := (GenRepo{Token: "plain"}).GetToken(); err != nil || token != "plain" {
+		t.Errorf("expected the token, got %q, %v", token, err)
+	}
+}
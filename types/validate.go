@@ -0,0 +1,409 @@
+package types
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+)
+
+// Validate checks the configuration for errors which would otherwise only show up while running.
+func (conf Conf) Validate() []error {
+	errs := []error{}
+
+	if conf.Cron != "" {
+		if _, err := cron.ParseStandard(conf.CronSpec()); err != nil {
+			errs = append(errs, fmt.Errorf("cron: %s", err.Error()))
+		}
+	}
+
+	if conf.Timezone != "" {
+		if _, err := time.LoadLocation(conf.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("timezone: %s", err.Error()))
+		}
+	}
+
+	if conf.Jitter != "" {
+		if _, err := time.ParseDuration(conf.Jitter); err != nil {
+			errs = append(errs, fmt.Errorf("jitter: %s", err.Error()))
+		}
+	}
+
+	if conf.Window.Start != "" || conf.Window.End != "" {
+		if err := conf.Window.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("window: %s", err.Error()))
+		}
+	}
+
+	switch strings.ToLower(conf.Order) {
+	case "", OrderLargest, OrderSmallest:
+	default:
+		errs = append(errs, fmt.Errorf("order: unknown order %s, use largest or smallest", conf.Order))
+	}
+
+	for _, source := range []struct {
+		hoster string
+		repos  []GenRepo
+	}{
+		{"github", conf.Source.Github},
+		{"gitea", conf.Source.Gitea},
+		{"gogs", conf.Source.Gogs},
+		{"gitlab", conf.Source.Gitlab},
+		{"bitbucket", conf.Source.BitBucket},
+		{"onedev", conf.Source.OneDev},
+		{"sourcehut", conf.Source.Sourcehut},
+		{"any", conf.Source.Any},
+	} {
+		for i, repo := range source.repos {
+			errs = append(errs, repo.validate(fmt.Sprintf("source.%s[%d]", source.hoster, i), source.hoster, true)...)
+		}
+	}
+
+	for _, destination := range []struct {
+		hoster string
+		repos  []GenRepo
+	}{
+		{"github", conf.Destination.Github},
+		{"gitea", conf.Destination.Gitea},
+		{"gogs", conf.Destination.Gogs},
+		{"gitlab", conf.Destination.Gitlab},
+	} {
+		for i, repo := range destination.repos {
+			errs = append(errs, repo.validate(fmt.Sprintf("destination.%s[%d]", destination.hoster, i), destination.hoster, false)...)
+		}
+	}
+
+	for i, l := range conf.Destination.Local {
+		prefix := fmt.Sprintf("destination.local[%d]", i)
+		if l.Path == "" {
+			errs = append(errs, fmt.Errorf("%s: path is missing", prefix))
+		}
+
+		if l.Compression != "" && l.Compression != "zip" && l.Compression != "zstd" {
+			errs = append(errs, fmt.Errorf("%s: unknown compression %s, use zip or zstd", prefix, l.Compression))
+		}
+
+		if l.Keep < 0 {
+			errs = append(errs, fmt.Errorf("%s: keep can't be negative", prefix))
+		}
+
+		if l.Cron != "" {
+			if _, err := cron.ParseStandard(l.Cron); err != nil {
+				errs = append(errs, fmt.Errorf("%s: cron: %s", prefix, err.Error()))
+			}
+		}
+
+		if l.MinFree != "" {
+			if _, err := ParseSize(l.MinFree); err != nil {
+				errs = append(errs, fmt.Errorf("%s: minfree: %s", prefix, err.Error()))
+			}
+		}
+	}
+
+	if (conf.Metrics.Prometheus.ListenAddr == "") != (conf.Metrics.Prometheus.Endpoint == "") {
+		errs = append(errs, fmt.Errorf("metrics.prometheus: listen_addr and endpoint have to be set together"))
+	}
+
+	for i, u := range conf.Metrics.Heartbeat.URLs {
+		if err := validateURL(u); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.heartbeat.urls[%d]: %s", i, err.Error()))
+		}
+	}
+
+	switch conf.Log.Format {
+	case "", LogConsole, LogJSON:
+	default:
+		errs = append(errs, fmt.Errorf("log.format: unknown value %s, use console or json", conf.Log.Format))
+	}
+
+	for _, level := range []struct {
+		name  string
+		value string
+	}{
+		{"log.level", conf.Log.Level},
+		{"log.file-logging.level", conf.Log.FileLogging.Level},
+	} {
+		if level.value == "" {
+			continue
+		}
+
+		if _, err := zerolog.ParseLevel(level.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", level.name, err.Error()))
+		}
+	}
+
+	if endpoint := conf.Metrics.Tracing.Endpoint; endpoint != "" {
+		if strings.Contains(endpoint, "://") || strings.Contains(endpoint, "/") {
+			errs = append(errs, fmt.Errorf("metrics.tracing.endpoint: %s has to be host:port", endpoint))
+		}
+	}
+
+	if conf.Metrics.Prometheus.Pushgateway.URL != "" {
+		if err := validateURL(conf.Metrics.Prometheus.Pushgateway.URL); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.prometheus.pushgateway: %s", err.Error()))
+		}
+	}
+
+	for i, check := range conf.Metrics.Heartbeat.Checks {
+		if err := validateURL(check.URL); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.heartbeat.checks[%d]: %s", i, err.Error()))
+		}
+	}
+
+	if conf.Metrics.Heartbeat.Timeout != "" {
+		if _, err := time.ParseDuration(conf.Metrics.Heartbeat.Timeout); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.heartbeat.timeout: %s", err.Error()))
+		}
+	}
+
+	pushers := conf.Metrics.PushConfigs.All()
+	names := []string{}
+	for name := range pushers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for i, p := range pushers[name] {
+			if p == nil {
+				continue
+			}
+
+			errs = append(errs, p.validate(name, fmt.Sprintf("metrics.push.%s[%d]", name, i))...)
+		}
+	}
+
+	if conf.HasWebhookConf() && conf.Webhook.Secret == "" {
+		errs = append(errs, fmt.Errorf("webhook.secret: required, without it anyone could trigger backups"))
+	}
+
+	if conf.Webhook.Debounce != "" {
+		if _, err := time.ParseDuration(conf.Webhook.Debounce); err != nil {
+			errs = append(errs, fmt.Errorf("webhook.debounce: %s", err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func (grepo GenRepo) validate(prefix, hoster string, source bool) []error {
+	errs := []error{}
+
+	if grepo.URL != "" && hoster != "any" {
+		if err := validateURL(grepo.URL); err != nil {
+			errs = append(errs, fmt.Errorf("%s: url: %s", prefix, err.Error()))
+		}
+	}
+
+	if grepo.URL == "" && (hoster == "any" || hoster == "gogs") {
+		errs = append(errs, fmt.Errorf("%s: url is missing", prefix))
+	}
+
+	if grepo.Token != "" && grepo.TokenFile != "" {
+		errs = append(errs, fmt.Errorf("%s: token and token_file are both set", prefix))
+	}
+
+	if grepo.TokenFile != "" {
+		if _, err := os.Stat(grepo.TokenFile); err != nil {
+			errs = append(errs, fmt.Errorf("%s: token_file: %s", prefix, err.Error()))
+		}
+	}
+
+	if grepo.SSHKey != "" && grepo.SSH {
+		if _, err := os.Stat(grepo.SSHKey); err != nil {
+			errs = append(errs, fmt.Errorf("%s: sshkey: %s", prefix, err.Error()))
+		}
+	}
+
+	if grepo.Cron != "" {
+		if _, err := cron.ParseStandard(grepo.Cron); err != nil {
+			errs = append(errs, fmt.Errorf("%s: cron: %s", prefix, err.Error()))
+		}
+	}
+
+	if source {
+		filter := grepo.Filter
+		if err := filter.ParseDuration(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: filter.lastactivity: %s", prefix, err.Error()))
+		}
+
+		for _, value := range []struct {
+			name  string
+			value string
+			parse func(string) error
+		}{
+			{"minsize", filter.MinSize, func(s string) error { _, err := ParseSize(s); return err }},
+			{"maxsize", filter.MaxSize, func(s string) error { _, err := ParseSize(s); return err }},
+			{"createdbefore", filter.CreatedBefore, func(s string) error { _, err := parseDate(s); return err }},
+			{"createdafter", filter.CreatedAfter, func(s string) error { _, err := parseDate(s); return err }},
+		} {
+			if value.value == "" {
+				continue
+			}
+
+			if err := value.parse(value.value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: filter.%s: %s", prefix, value.name, err.Error()))
+			}
+		}
+
+		if filter.Expression != "" {
+			if _, err := compileExpression(filter.Expression); err != nil {
+				errs = append(errs, fmt.Errorf("%s: filter.expression: %s", prefix, err.Error()))
+			}
+		}
+
+		switch strings.ToLower(filter.Visibility) {
+		case "", VisibilityPublic, VisibilityPrivate, VisibilityInternal, "unlisted":
+		default:
+			errs = append(errs, fmt.Errorf("%s: filter.visibility: unknown visibility %s, use public, private or internal", prefix, filter.Visibility))
+		}
+
+		switch strings.ToLower(grepo.Forks) {
+		case "", ForksInclude, ForksExclude, ForksOnly:
+		default:
+			errs = append(errs, fmt.Errorf("%s: forks: unknown value %s, use include, exclude or only", prefix, grepo.Forks))
+		}
+
+		// only github and gitlab can compare a fork with its parent
+		if grepo.DivergedForks && hoster != "github" && hoster != "gitlab" {
+			errs = append(errs, fmt.Errorf("%s: divergedforks is only supported by github and gitlab", prefix))
+		}
+
+		exclude := GetMap(grepo.Exclude)
+		for _, include := range grepo.Include {
+			if exclude[include] {
+				errs = append(errs, fmt.Errorf("%s: %s is included and excluded", prefix, include))
+			}
+		}
+
+		excludeorgs := GetMap(grepo.ExcludeOrgs)
+		for _, include := range grepo.IncludeOrgs {
+			if excludeorgs[include] {
+				errs = append(errs, fmt.Errorf("%s: organization %s is included and excluded", prefix, include))
+			}
+		}
+
+		for _, list := range []struct {
+			name     string
+			patterns []string
+		}{
+			{"include", grepo.Include},
+			{"exclude", grepo.Exclude},
+			{"includeorgs", grepo.IncludeOrgs},
+			{"excludeorgs", grepo.ExcludeOrgs},
+		} {
+			for _, pattern := range list.patterns {
+				if err := validatePattern(pattern); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %s: %s: %s", prefix, list.name, pattern, err.Error()))
+				}
+			}
+		}
+	} else if grepo.Token == "" && grepo.TokenFile == "" {
+		errs = append(errs, fmt.Errorf("%s: a token is needed for destinations", prefix))
+	}
+
+	return errs
+}
+
+func (p PushConfig) validate(service, prefix string) []error {
+	errs := []error{}
+
+	if service != "smtp" {
+		if err := validateURL(p.Url); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", prefix, err.Error()))
+		}
+	}
+
+	switch service {
+	case "smtp":
+		if parsed, err := url.Parse(p.Url); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", prefix, err.Error()))
+		} else if parsed.Scheme != "smtp" || parsed.Hostname() == "" {
+			errs = append(errs, fmt.Errorf("%s: %s is not a smtp://host:port url", prefix, p.Url))
+		}
+
+		if p.From == "" || len(p.To) == 0 {
+			errs = append(errs, fmt.Errorf("%s: smtp needs from and to", prefix))
+		}
+
+		for _, address := range append([]string{p.From}, p.To...) {
+			if _, err := mail.ParseAddress(address); address != "" && err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %s", prefix, address, err.Error()))
+			}
+		}
+
+		switch strings.ToLower(p.TLS) {
+		case "", SMTPStartTLS, SMTPImplicit, SMTPNone:
+		default:
+			errs = append(errs, fmt.Errorf("%s: tls: unknown value %s, use starttls, implicit or none", prefix, p.TLS))
+		}
+	case "matrix":
+		if p.Token == "" || p.Room == "" {
+			errs = append(errs, fmt.Errorf("%s: matrix needs a token and a room", prefix))
+		}
+	case "webhook":
+		switch strings.ToUpper(p.Method) {
+		case "", http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			errs = append(errs, fmt.Errorf("%s: method: unknown value %s, use POST, PUT or PATCH", prefix, p.Method))
+		}
+	}
+
+	// the templates are parsed like they are rendered
+	for _, tmpl := range []struct {
+		name  string
+		value string
+	}{
+		{"title", p.Title},
+		{"message", p.Message},
+	} {
+		if _, err := template.New(tmpl.name).Parse(tmpl.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", prefix, err.Error()))
+		}
+	}
+
+	var err error
+	if service == "smtp" {
+		_, err = htmltemplate.New("body").Funcs(MailFuncs).Parse(p.Body)
+	} else {
+		_, err = template.New("body").Funcs(BodyFuncs).Parse(p.Body)
+	}
+
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %s", prefix, err.Error()))
+	}
+
+	switch strings.ToLower(p.On) {
+	case "", NotifyAlways, NotifyFailure, NotifySuccess, NotifyChange:
+	default:
+		errs = append(errs, fmt.Errorf("%s: on: unknown value %s, use always, failure, success or change", prefix, p.On))
+	}
+
+	return errs
+}
+
+func validateURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%s is not a http(s) url", u)
+	}
+
+	if parsed.Host == "" {
+		return fmt.Errorf("%s has no host", u)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateFindsErrors(t *testing.T) {
+	t.Parallel()
+
+	conf := Conf{
+		Cron: "redshirt",
+		Source: Source{
+			Github: []GenRepo{{
+				Token:     "token",
+				TokenFile: "/does/not/exist",
+				Filter:    Filter{LastActivityString: "1x"},
+			}},
+		},
+		Destination: Destination{
+			Local: []Local{{Path: "/backup", Compression: "rar"}},
+		},
+	}
+
+	if errs := conf.Validate(); len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestValidateValidConf(t *testing.T) {
+	t.Parallel()
+
+	conf := Conf{
+		Cron:   "0 22 * * *",
+		Source: Source{Gitea: []GenRepo{{URL: "https://gitea.com", Filter: Filter{LastActivityString: "1y"}}}},
+		Destination: Destination{
+			Local: []Local{{Path: "/backup", Compression: "zstd", Keep: 5}},
+		},
+	}
+
+	if errs := conf.Validate(); len(errs) != 0 {
+		t.Errorf("valid configuration has errors: %v", errs)
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	t.Parallel()
+
+	smtp := PushConfig{Url: "smtp://mail.example.com", From: "gickup@example.com", To: []string{"me@example.com"}}
+
+	smtp.Body = `{{range lines .Message}}{{.}}<br>{{end}}`
+	if errs := smtp.validate("smtp", "smtp"); len(errs) != 0 {
+		t.Errorf("the smtp body with lines has errors: %v", errs)
+	}
+
+	smtp.Body = `{{json .Message}}`
+	if errs := smtp.validate("smtp", "smtp"); len(errs) != 1 {
+		t.Errorf("expected 1 error of the smtp body with json, got %v", errs)
+	}
+
+	webhook := PushConfig{Url: "https://example.com", Body: `{"text": {{json .Message}}}`}
+	if errs := webhook.validate("webhook", "webhook"); len(errs) != 0 {
+		t.Errorf("the webhook body with json has errors: %v", errs)
+	}
+}
+
+func TestValidateDivergedForks(t *testing.T) {
+	t.Parallel()
+
+	conf := Conf{Source: Source{
+		Github: []GenRepo{{Token: "token", DivergedForks: true}},
+		Gitea:  []GenRepo{{URL: "https://gitea.com", DivergedForks: true}},
+	}}
+
+	if errs := conf.Validate(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "source.gitea[0]") {
+		t.Errorf("expected an error for gitea, got %v", errs)
+	}
+}
+
+func TestValidateWebhookSecret(t *testing.T) {
+	t.Parallel()
+
+	conf := Conf{Webhook: WebhookConfig{ListenAddr: ":8080"}}
+	if errs := conf.Validate(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "webhook.secret") {
+		t.Errorf("expected an error for the missing secret, got %v", errs)
+	}
+
+	conf.Webhook.Secret = "secret"
+	if errs := conf.Validate(); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cooperspencer/gickup/types"
+	"github.com/rs/zerolog/log"
+)
+
+// validate checks the configfiles and returns the exit code, with schema set it prints the JSON schema instead.
+func validate(configfiles []string, schema bool) int {
+	if schema {
+		data, err := types.Schema()
+		if err != nil {
+			log.Error().Str("stage", "validate").Msg(err.Error())
+			return 1
+		}
+
+		fmt.Println(string(data))
+
+		return 0
+	}
+
+	code := 0
+	for _, f := range configfiles {
+		confs, err := loadConfigFile(f, true)
+		if err != nil {
+			log.Error().
+				Str("stage", "validate").
+				Str("file", f).
+				Msg(err.Error())
+			code = 1
+
+			continue
+		}
+
+		valid := true
+		for num, conf := range confs {
+			for _, err := range conf.Validate() {
+				log.Error().
+					Str("stage", "validate").
+					Str("file", f).
+					Int("config", num).
+					Msg(err.Error())
+				valid = false
+			}
+		}
+
+		if valid {
+			log.Info().
+				Str("stage", "validate").
+				Str("file", f).
+				Msgf("%s is valid", types.Green(f))
+		} else {
+			code = 1
+		}
+	}
+
+	return code
+}
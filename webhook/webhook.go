@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cooperspencer/gickup/logger"
+	"github.com/cooperspencer/gickup/types"
+)
+
+// maxBodySize limits the size of accepted payloads.
+const maxBodySize = 25 << 20
+
+// Event is a push to a repository.
+type Event struct {
+	Hoster string
+	Owner  string
+	Name   string
+	URLs   []string
+}
+
+// Key identifies the repository of the event, repositories of different hosters can have the same name.
+func (e Event) Key() string {
+	return strings.ToLower(fmt.Sprintf("%s/%s/%s", e.Hoster, e.Owner, e.Name))
+}
+
+// Matches checks if the event was sent for repo.
+func (e Event) Matches(repo types.Repo) bool {
+	for _, u := range e.URLs {
+		if u == "" {
+			continue
+		}
+
+		if normalize(u) == normalize(repo.URL) || normalize(u) == normalize(repo.SSHURL) {
+			return true
+		}
+	}
+
+	return strings.EqualFold(e.Owner, repo.Owner) &&
+		strings.EqualFold(e.Name, repo.Name) &&
+		strings.EqualFold(e.Hoster, repo.Hoster)
+}
+
+// normalize strips the scheme, credentials and .git suffix of a clone url.
+func normalize(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+
+	if i := strings.Index(u, "@"); i >= 0 {
+		u = u[i+1:]
+	}
+
+	u = strings.Replace(u, ":", "/", 1)
+	u = strings.TrimSuffix(u, "/")
+
+	return strings.TrimSuffix(u, ".git")
+}
+
+type repository struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	CloneURL string `json:"clone_url"`
+	HTMLURL  string `json:"html_url"`
+	SSHURL   string `json:"ssh_url"`
+	Owner    struct {
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	} `json:"owner"`
+}
+
+type project struct {
+	Name              string `json:"name"`
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	GitHTTPURL        string `json:"git_http_url"`
+	GitSSHURL         string `json:"git_ssh_url"`
+	WebURL            string `json:"web_url"`
+}
+
+type payload struct {
+	Repository repository `json:"repository"`
+	Project    project    `json:"project"`
+}
+
+// ErrIgnored is returned for valid requests which are not push events.
+var ErrIgnored = fmt.Errorf("not a push event")
+
+// Parse verifies the signature of the request and returns the pushed repository, without a secret
+// every request is refused.
+func Parse(header http.Header, body []byte, secret string) (Event, error) {
+	var hoster, event string
+
+	if secret == "" {
+		return Event{}, fmt.Errorf("no secret configured")
+	}
+
+	switch {
+	case header.Get("X-Gitea-Event") != "":
+		hoster, event = "gitea", header.Get("X-Gitea-Event")
+		if err := verifyHMAC(body, header.Get("X-Gitea-Signature"), secret); err != nil {
+			return Event{}, err
+		}
+	case header.Get("X-Gogs-Event") != "":
+		hoster, event = "gogs", header.Get("X-Gogs-Event")
+		if err := verifyHMAC(body, header.Get("X-Gogs-Signature"), secret); err != nil {
+			return Event{}, err
+		}
+	case header.Get("X-Gitlab-Event") != "":
+		hoster, event = "gitlab", header.Get("X-Gitlab-Event")
+		if subtle.ConstantTimeCompare([]byte(header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			return Event{}, fmt.Errorf("invalid token")
+		}
+	case header.Get("X-GitHub-Event") != "":
+		hoster, event = "github", header.Get("X-GitHub-Event")
+		if err := verifyHMAC(body, strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256="), secret); err != nil {
+			return Event{}, err
+		}
+	default:
+		return Event{}, fmt.Errorf("unknown webhook sender")
+	}
+
+	if event != "push" && event != "Push Hook" && event != "Tag Push Hook" {
+		return Event{}, ErrIgnored
+	}
+
+	p := payload{}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Event{}, err
+	}
+
+	e := Event{Hoster: hoster}
+	if hoster == "gitlab" {
+		e.Name = p.Project.Path
+		if i := strings.LastIndex(p.Project.PathWithNamespace, "/"); i >= 0 {
+			e.Owner = p.Project.PathWithNamespace[:i]
+		}
+		e.URLs = []string{p.Project.GitHTTPURL, p.Project.GitSSHURL, p.Project.WebURL}
+		e.Hoster = types.GetHost(p.Project.WebURL)
+	} else {
+		e.Name = p.Repository.Name
+		e.Owner = p.Repository.Owner.Login
+		if e.Owner == "" {
+			e.Owner = p.Repository.Owner.Username
+		}
+		e.URLs = []string{p.Repository.CloneURL, p.Repository.SSHURL, p.Repository.HTMLURL}
+		e.Hoster = types.GetHost(p.Repository.HTMLURL)
+	}
+
+	if e.Name == "" {
+		return Event{}, fmt.Errorf("payload contains no repository")
+	}
+
+	return e, nil
+}
+
+func verifyHMAC(body []byte, signature, secret string) error {
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// Debouncer calls fn once per repository after no more pushes arrived for delay.
+type Debouncer struct {
+	mu     sync.Mutex
+	delay  time.Duration
+	fn     func(Event)
+	timers map[string]*time.Timer
+}
+
+// NewDebouncer TODO.
+func NewDebouncer(delay time.Duration, fn func(Event)) *Debouncer {
+	return &Debouncer{delay: delay, fn: fn, timers: map[string]*time.Timer{}}
+}
+
+// Push schedules fn for the repository of e, pushes in the meantime postpone it.
+func (d *Debouncer) Push(e Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := e.Key()
+	if t, ok := d.timers[key]; ok {
+		t.Stop()
+	}
+
+	d.timers[key] = time.AfterFunc(d.delay, func() {
+		d.mu.Lock()
+		delete(d.timers, key)
+		d.mu.Unlock()
+
+		d.fn(e)
+	})
+}
+
+// Handler returns the http handler receiving the webhooks.
+func Handler(conf types.WebhookConfig, trigger func(Event)) http.Handler {
+	debouncer := NewDebouncer(conf.GetDebounce(), trigger)
+	secret := conf.GetSecret()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		event, err := Parse(r.Header, body, secret)
+		if err == ErrIgnored {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err != nil {
+			logger.Background().Warn().
+				Str("stage", "webhook").
+				Str("remote", r.RemoteAddr).
+				Msg(err.Error())
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		logger.Background().Info().
+			Str("stage", "webhook").
+			Str("hoster", event.Hoster).
+			Msgf("received push for %s", types.Blue(event.Key()))
+
+		debouncer.Push(event)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Serve starts the webhook listener.
+func Serve(conf types.WebhookConfig, trigger func(Event)) {
+	logger.Background().Info().
+		Str("listenAddr", conf.ListenAddr).
+		Str("endpoint", conf.GetEndpoint()).
+		Msg("Starting webhook listener")
+
+	mux := http.NewServeMux()
+	mux.Handle(conf.GetEndpoint(), Handler(conf, trigger))
+
+	err := http.ListenAndServe(conf.ListenAddr, mux)
+	logger.Background().Fatal().
+		Str("listenAddr", conf.ListenAddr).
+		Str("endpoint", conf.GetEndpoint()).
+		Msg(err.Error())
+}
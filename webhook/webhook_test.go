@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cooperspencer/gickup/types"
+)
+
+const githubPush = `{"repository":{"name":"gickup","full_name":"cooperspencer/gickup",` +
+	`"clone_url":"https://github.com/cooperspencer/gickup.git","html_url":"https://github.com/cooperspencer/gickup",` +
+	`"ssh_url":"git@github.com:cooperspencer/gickup.git","owner":{"login":"cooperspencer"}}}`
+
+func sign(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseGithub(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "push")
+	header.Set("X-Hub-Signature-256", "sha256="+sign(githubPush, "secret"))
+
+	e, err := Parse(header, []byte(githubPush), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := types.Repo{
+		Name:   "gickup",
+		Owner:  "cooperspencer",
+		Hoster: "github.com",
+		URL:    "https://github.com/cooperspencer/gickup.git",
+	}
+	if !e.Matches(repo) {
+		t.Error("event doesn't match the pushed repository")
+	}
+
+	repo.URL = "https://github.com/cooperspencer/other.git"
+	repo.Name = "other"
+	if e.Matches(repo) {
+		t.Error("event matches another repository")
+	}
+}
+
+func TestParseInvalidSignature(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("X-Gitea-Event", "push")
+	header.Set("X-Gitea-Signature", sign(githubPush, "wrong"))
+
+	if _, err := Parse(header, []byte(githubPush), "secret"); err == nil {
+		t.Error("invalid signature was accepted")
+	}
+}
+
+func TestParseWithoutSecret(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "push")
+	if _, err := Parse(header, []byte(githubPush), ""); err == nil {
+		t.Error("unsigned request was accepted without a secret")
+	}
+
+	header = http.Header{}
+	header.Set("X-Gitlab-Event", "Push Hook")
+	if _, err := Parse(header, []byte(`{"project":{"path":"gickup"}}`), ""); err == nil {
+		t.Error("gitlab request without token was accepted without a secret")
+	}
+}
+
+func TestParseGitlabIgnored(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("X-Gitlab-Event", "Issue Hook")
+	header.Set("X-Gitlab-Token", "secret")
+
+	if _, err := Parse(header, []byte(`{}`), "secret"); err != ErrIgnored {
+		t.Errorf("expected ErrIgnored, got %v", err)
+	}
+}
+
+func TestDebouncerKeepsHostersApart(t *testing.T) {
+	done := make(chan Event, 2)
+	debouncer := NewDebouncer(10*time.Millisecond, func(e Event) { done <- e })
+
+	debouncer.Push(Event{Hoster: "github.com", Owner: "me", Name: "dotfiles"})
+	debouncer.Push(Event{Hoster: "gitea.example.com", Owner: "me", Name: "dotfiles"})
+
+	hosters := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-done:
+			hosters[e.Hoster] = true
+		case <-time.After(time.Second):
+			t.Fatal("a push was merged with the push of another hoster")
+		}
+	}
+
+	if len(hosters) != 2 {
+		t.Errorf("expected pushes of 2 hosters, got %v", hosters)
+	}
+}
@@ -90,11 +90,20 @@ func Get(conf *types.Conf) ([]types.Repo, bool) {
 				name = name[:strings.LastIndex(name, ".git")]
 			}
 
+			token, err := repo.GetToken()
+			if err != nil {
+				log.Error().
+					Str("stage", "whatever").
+					Msg(err.Error())
+
+				continue
+			}
+
 			repos = append(repos, types.Repo{
 				Name:          name,
 				URL:           repo.URL,
 				SSHURL:        repo.URL,
-				Token:         repo.GetToken(),
+				Token:         token,
 				Defaultbranch: main,
 				Origin:        repo,
 				Owner:         "git",